@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"wynn_bot/metrics"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// discord asks for a heartbeat about every 41 seconds, missing a few in a
+// row means the gateway connection is gone
+const heartbeatStale = 2 * time.Minute
+
+// what a command ended with, for wynn_bot_commands_total
+const (
+	outcomeOK         = "ok"
+	outcomeFallback   = "fallback" // answered with text after the image failed
+	outcomeNotFound   = "not_found"
+	outcomeInvalid    = "invalid"
+	outcomeAPIError   = "api_error"
+	outcomeFailed     = "failed"
+	outcomeBusy       = "busy"
+	outcomeExpired    = "expired"
+	outcomeRestarting = "restarting"
+)
+
+var (
+	commandsHandled = metrics.Default.NewCounter("wynn_bot_commands_total",
+		"Slash commands handled, by command and outcome.", "command", "outcome")
+	renderDuration = metrics.Default.NewHistogram("wynn_bot_render_duration_seconds",
+		"Time spent drawing and encoding a card, by card.", nil, "card")
+	_ = metrics.Default.NewGauge("wynn_bot_render_queue_depth",
+		"Renders waiting for a worker.", func() float64 {
+			if renders == nil {
+				return 0
+			}
+			return float64(renders.Len())
+		})
+)
+
+// countCommand records how the command in i ended.
+func countCommand(i *discordgo.InteractionCreate, outcome string) {
+	name := "unknown"
+	if data, ok := i.Data.(discordgo.ApplicationCommandInteractionData); ok {
+		name = data.Name
+	}
+	commandsHandled.Inc(name, outcome)
+}
+
+// status is what /healthz and /readyz answer with, a 503 unless OK.
+type status struct {
+	OK      bool           `json:"ok"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// adminHandler routes:
+//
+//	GET /healthz  the process is up and connected to discord
+//	GET /readyz   it can take commands
+//	GET /metrics  Prometheus metrics
+func adminHandler(health, ready func() status, registry *metrics.Registry) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", statusHandler(health))
+	mux.HandleFunc("GET /readyz", statusHandler(ready))
+	mux.Handle("GET /metrics", registry.Handler())
+	return mux
+}
+
+func statusHandler(check func() status) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		st := check()
+		w.Header().Set("Content-Type", "application/json")
+		if !st.OK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(st)
+	}
+}
+
+// gatewayHealth reports whether the session is connected and still
+// hearing back from discord.
+func gatewayHealth(s *discordgo.Session) func() status {
+	return func() status {
+		s.RLock()
+		connected, lastAck := s.DataReady, s.LastHeartbeatAck
+		s.RUnlock()
+
+		details := map[string]any{"connected": connected}
+		fresh := false
+		if !lastAck.IsZero() {
+			details["lastHeartbeat"] = lastAck
+			fresh = time.Since(lastAck) < heartbeatStale
+		}
+		return status{OK: connected && fresh, Details: details}
+	}
+}
+
+// readiness reports whether the commands are registered, every asset the
+// cards can't draw without was found at startup, and the bot isn't shutting
+// down. Badges and banner patterns have fallbacks, so they don't count.
+func readiness(registered *atomic.Bool, missingAssets []string, bot *lifecycle) func() status {
+	return func() status {
+		bot.mu.Lock()
+		closing := bot.closing
+		bot.mu.Unlock()
+
+		details := map[string]any{
+			"commandsRegistered": registered.Load(),
+			"shuttingDown":       closing,
+		}
+		if len(missingAssets) > 0 {
+			details["missingAssets"] = missingAssets
+		}
+		return status{OK: registered.Load() && len(missingAssets) == 0 && !closing, Details: details}
+	}
+}
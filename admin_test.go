@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"wynn_bot/metrics"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func adminGet(t *testing.T, h http.Handler, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	body, _ := io.ReadAll(rec.Body)
+	return rec.Code, string(body)
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	var registered atomic.Bool
+	bot := &lifecycle{}
+	healthy := true
+	health := func() status { return status{OK: healthy} }
+	h := adminHandler(health, readiness(&registered, nil, bot), metrics.Default)
+
+	if code, _ := adminGet(t, h, "/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz = %d while healthy", code)
+	}
+	healthy = false
+	if code, _ := adminGet(t, h, "/healthz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/healthz = %d while unhealthy", code)
+	}
+
+	if code, _ := adminGet(t, h, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz = %d before the commands were registered", code)
+	}
+	registered.Store(true)
+	if code, _ := adminGet(t, h, "/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz = %d once registered", code)
+	}
+	bot.closing = true
+	code, body := adminGet(t, h, "/readyz")
+	var st status
+	if err := json.Unmarshal([]byte(body), &st); err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusServiceUnavailable || st.Details["shuttingDown"] != true {
+		t.Errorf("/readyz = %d %s while shutting down", code, body)
+	}
+
+	countCommand(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		Data: discordgo.ApplicationCommandInteractionData{Name: "stats"},
+	}}, outcomeOK)
+	_, body = adminGet(t, h, "/metrics")
+	for _, want := range []string{
+		`wynn_bot_commands_total{command="stats",outcome="ok"} 1`,
+		"# TYPE wynn_bot_render_duration_seconds histogram",
+		"wynn_bot_render_queue_depth 0",
+		"wynn_api_cache_hit_ratio",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics is missing %q", want)
+		}
+	}
+}
+
+func TestReadinessListsMissingAssets(t *testing.T) {
+	var registered atomic.Bool
+	registered.Store(true)
+	st := readiness(&registered, []string{"statscard/fonts/minecraft.ttf"}, &lifecycle{})()
+	if st.OK || st.Details["missingAssets"] == nil {
+		t.Errorf("got %+v with an asset missing", st)
+	}
+}
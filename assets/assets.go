@@ -0,0 +1,235 @@
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// Config says where the pipeline reads sources and writes the images the cards draw.
+type Config struct {
+	RanksSource   string // rank badges as svg or png, rasterized svgs are written here as png
+	RanksOutput   string // upscaled rank badges
+	BannersSource string // banner patterns as svg or png
+	BannersOutput string // banner patterns at BannerWidth x BannerHeight
+
+	Scale                     int // rank badge upscale factor
+	BannerWidth, BannerHeight int
+}
+
+var DefaultConfig = Config{
+	RanksSource:   "statscard/ranks",
+	RanksOutput:   "statscard/ranks_upscale",
+	BannersSource: "statscard/banner",
+	BannersOutput: "statscard/banner",
+	Scale:         4,
+	BannerWidth:   160,
+	BannerHeight:  320,
+}
+
+// Build rasterizes and upscales every asset in the manifest that has a source.
+// Output only depends on the sources, and files whose pixels wouldn't change are left alone.
+func Build(cfg Config) error {
+	for _, rank := range Ranks {
+		if err := buildRank(cfg, rank); err != nil {
+			return fmt.Errorf("rank %s: %v", rank, err)
+		}
+	}
+	for _, pattern := range BannerPatterns {
+		if err := buildBanner(cfg, pattern); err != nil {
+			return fmt.Errorf("banner pattern %s: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Verify lists the manifest entries with no output file, an empty result means the cards can draw everything.
+func Verify(cfg Config) []string {
+	var missing []string
+	for _, rank := range Ranks {
+		path := filepath.Join(cfg.RanksOutput, RankFile(rank))
+		if !exists(path) {
+			missing = append(missing, path)
+		}
+	}
+	for _, pattern := range BannerPatterns {
+		path := filepath.Join(cfg.BannersOutput, BannerFile(pattern))
+		if !exists(path) {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+// VerifyRequired lists the Required files that are missing, relative to
+// the working directory.
+func VerifyRequired() []string {
+	var missing []string
+	for _, path := range Required {
+		if !exists(path) {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+func buildRank(cfg Config, rank string) error {
+	pngPath := filepath.Join(cfg.RanksSource, RankFile(rank))
+	svgPath := filepath.Join(cfg.RanksSource, "rank_"+rank+".svg")
+
+	if exists(svgPath) {
+		// badges are pixel art, so they're rasterized at their own size and scaled up afterwards
+		img, err := rasterizeSVG(svgPath, 0, 0)
+		if err != nil {
+			return err
+		}
+		if err := writePNG(pngPath, img); err != nil {
+			return err
+		}
+	}
+
+	if !exists(pngPath) {
+		log.Printf("no source for rank %s, skipping", rank)
+		return nil
+	}
+
+	img, err := readPNG(pngPath)
+	if err != nil {
+		return err
+	}
+	return writePNG(filepath.Join(cfg.RanksOutput, RankFile(rank)), Upscale(img, cfg.Scale))
+}
+
+func buildBanner(cfg Config, pattern string) error {
+	svgPath := filepath.Join(cfg.BannersSource, pattern+".svg")
+	pngPath := filepath.Join(cfg.BannersSource, BannerFile(pattern))
+	outPath := filepath.Join(cfg.BannersOutput, BannerFile(pattern))
+
+	var img image.Image
+	var err error
+	switch {
+	case exists(svgPath):
+		img, err = rasterizeSVG(svgPath, cfg.BannerWidth, cfg.BannerHeight)
+	case exists(pngPath):
+		if pngPath == outPath {
+			return nil
+		}
+		img, err = readPNG(pngPath)
+	default:
+		log.Printf("no source for banner pattern %s, skipping", pattern)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return writePNG(outPath, img)
+}
+
+// rasterizeSVG draws an svg at w x h, or at its viewBox size if either is 0.
+func rasterizeSVG(path string, w, h int) (*image.NRGBA, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open svg: %v", err)
+	}
+	defer file.Close()
+
+	icon, err := oksvg.ReadIconStream(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse svg: %v", err)
+	}
+
+	if w == 0 || h == 0 {
+		w, h = int(icon.ViewBox.W+0.5), int(icon.ViewBox.H+0.5)
+	}
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("svg has no size")
+	}
+
+	icon.SetTarget(0, 0, float64(w), float64(h))
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	scanner := rasterx.NewScannerGV(w, h, rgba, rgba.Bounds())
+	icon.Draw(rasterx.NewDasher(w, h, scanner), 1)
+
+	return toNRGBA(rgba), nil
+}
+
+// Upscale scales img by an integer factor with nearest neighbour, keeping pixel art sharp.
+func Upscale(img image.Image, scale int) *image.NRGBA {
+	src := toNRGBA(img)
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx()*scale, b.Dy()*scale))
+
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			si := src.PixOffset(b.Min.X+x/scale, b.Min.Y+y/scale)
+			di := dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok {
+		return n
+	}
+	b := img.Bounds()
+	n := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(n, n.Bounds(), img, b.Min, draw.Src)
+	return n
+}
+
+func readPNG(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %v", err)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+	return img, nil
+}
+
+// writePNG skips the write when the file already holds the same pixels, so
+// rerunning the pipeline doesn't touch files that were encoded by another tool.
+func writePNG(path string, img image.Image) error {
+	n := toNRGBA(img)
+	if old, err := readPNG(path); err == nil && samePixels(toNRGBA(old), n) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, n); err != nil {
+		return fmt.Errorf("failed to encode %s: %v", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	log.Printf("wrote %s", path)
+	return nil
+}
+
+func samePixels(a, b *image.NRGBA) bool {
+	return a.Rect.Size() == b.Rect.Size() && bytes.Equal(a.Pix, b.Pix)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, os.ErrNotExist)
+}
@@ -0,0 +1,114 @@
+package assets
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestUpscale(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.SetNRGBA(0, 0, red)
+	src.SetNRGBA(1, 0, blue)
+
+	dst := Upscale(src, 3)
+	if size := dst.Bounds().Size(); size != image.Pt(6, 3) {
+		t.Fatalf("upscaled to %v, want 6x3", size)
+	}
+	for y := range 3 {
+		for x := range 6 {
+			want := red
+			if x >= 3 {
+				want = blue
+			}
+			if got := dst.NRGBAAt(x, y); got != want {
+				t.Errorf("pixel %d,%d is %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 8 4"><rect width="8" height="4" fill="#ff0000"/></svg>`
+
+func TestBuildAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{
+		RanksSource:   filepath.Join(dir, "ranks"),
+		RanksOutput:   filepath.Join(dir, "ranks_upscale"),
+		BannersSource: filepath.Join(dir, "banner_src"),
+		BannersOutput: filepath.Join(dir, "banner"),
+		Scale:         2,
+		BannerWidth:   10,
+		BannerHeight:  20,
+	}
+	if err := writePNG(filepath.Join(cfg.RanksSource, RankFile("vip")), image.NewNRGBA(image.Rect(0, 0, 5, 3))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.RanksSource, "rank_hero.svg"), []byte(testSVG), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(cfg.BannersSource, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.BannersSource, "CROSS.svg"), []byte(testSVG), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Build(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, size := range map[string]image.Point{
+		filepath.Join(cfg.RanksOutput, RankFile("vip")):       image.Pt(10, 6),
+		filepath.Join(cfg.RanksSource, RankFile("hero")):      image.Pt(8, 4), // the svg at its own size
+		filepath.Join(cfg.RanksOutput, RankFile("hero")):      image.Pt(16, 8),
+		filepath.Join(cfg.BannersOutput, BannerFile("CROSS")): image.Pt(10, 20),
+	} {
+		img, err := readPNG(path)
+		if err != nil {
+			t.Errorf("%s wasn't built: %v", path, err)
+			continue
+		}
+		if got := img.Bounds().Size(); got != size {
+			t.Errorf("%s is %v, want %v", path, got, size)
+		}
+	}
+
+	missing := Verify(cfg)
+	if want := len(Ranks) - 2 + len(BannerPatterns) - 1; len(missing) != want {
+		t.Errorf("%d missing, want %d", len(missing), want)
+	}
+	for _, built := range []string{
+		filepath.Join(cfg.RanksOutput, RankFile("vip")),
+		filepath.Join(cfg.RanksOutput, RankFile("hero")),
+		filepath.Join(cfg.BannersOutput, BannerFile("CROSS")),
+	} {
+		if slices.Contains(missing, built) {
+			t.Errorf("%s is reported missing after it was built", built)
+		}
+	}
+}
+
+// TestShippedAssets keeps the manifest in step with the files in the repo.
+func TestShippedAssets(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if missing := Verify(DefaultConfig); len(missing) > 0 {
+		t.Errorf("the repo is missing %v", missing)
+	}
+	if missing := VerifyRequired(); len(missing) > 0 {
+		t.Errorf("the repo is missing %v", missing)
+	}
+}
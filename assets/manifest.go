@@ -0,0 +1,92 @@
+package assets
+
+// Ranks are the badge names the api can return, rank_<name> is the file the
+// rankBadge url points at. "none" is ours, drawn for players without a rank.
+var Ranks = []string{
+	"none",
+	"vip",
+	"vipplus",
+	"hero",
+	"champion",
+	"media",
+	"item",
+	"moderator",
+	"administrator",
+	"builder",
+	"gamemaster",
+	"cmd",
+	"hybrid",
+	"qa",
+	"music",
+}
+
+// BannerPatterns are the guild banner layer patterns the api can return.
+var BannerPatterns = []string{
+	"SQUARE_BOTTOM_LEFT",     // bottom left quad
+	"SQUARE_BOTTOM_RIGHT",    // bottom right quad
+	"SQUARE_TOP_LEFT",        // top left quad
+	"SQUARE_TOP_RIGHT",       // top right quad
+	"STRIPE_BOTTOM",          // bottom stripe
+	"STRIPE_TOP",             // top stripe
+	"STRIPE_LEFT",            // left stripe
+	"STRIPE_RIGHT",           // right stripe
+	"STRIPE_CENTER",          // vertical stripe
+	"STRIPE_MIDDLE",          // horizontal stripe
+	"STRIPE_DOWNRIGHT",       // major diagonal
+	"STRIPE_DOWNLEFT",        // minor diagonal
+	"STRIPE_SMALL",           // river
+	"CROSS",                  // cross
+	"TRIANGLE_BOTTOM",        // bottom triangle
+	"TRIANGLE_TOP",           // top triangle
+	"TRIANGLES_BOTTOM",       // bottom triangles
+	"TRIANGLES_TOP",          // top triangles
+	"DIAGONAL_LEFT",          // top left
+	"DIAGONAL_RIGHT",         // bottom right
+	"DIAGONAL_LEFT_MIRROR",   // bottom left
+	"DIAGONAL_RIGHT_MIRROR",  // top right
+	"HALF_VERTICAL",          // left half
+	"HALF_HORIZONTAL",        // top half
+	"HALF_VERTICAL_MIRROR",   // right half
+	"HALF_HORIZONTAL_MIRROR", // bottom half
+	"BORDER",                 // border
+	"CREEPER",                // creeper
+	"GRADIENT",               // gradient down
+	"BRICKS",                 // bricks
+	"SKULL",                  // skull
+	"MOJANG",                 // thing
+	"CURLY_BORDER",           // triangle border
+	"STRAIGHT_CROSS",         // horizontal cross
+	"GRADIENT_UP",            // gradient up
+	"FLOWER",                 // flower
+	"RHOMBUS_MIDDLE",         // rhombus
+	"CIRCLE_MIDDLE",          // circle
+}
+
+// Required are the files, from the repo root, the cards can't be drawn
+// without. The ranks and patterns above aren't among them: a missing badge
+// is generated and a missing pattern is left out of the banner.
+var Required = []string{
+	"statscard/fonts/minecraft.ttf",
+	"statscard/fonts/comfortaa.ttf",
+	"statscard/fonts/comfortaa_bold.ttf",
+	"statscard/fonts/nunito.ttf",
+	"statscard/images/background.png",
+	"statscard/images/footer.png",
+	"statscard/classes/ARCHER.png",
+	"statscard/classes/WARRIOR.png",
+	"statscard/classes/ASSASSIN.png",
+	"statscard/classes/MAGE.png",
+	"statscard/classes/SHAMAN.png",
+	"statscard/data/raids.json",
+	"statscard/data/dungeons.json",
+}
+
+// RankFile is the file name a rank badge is stored under.
+func RankFile(rank string) string {
+	return "rank_" + rank + ".png"
+}
+
+// BannerFile is the file name a banner pattern is stored under.
+func BannerFile(pattern string) string {
+	return pattern + ".png"
+}
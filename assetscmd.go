@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"wynn_bot/assets"
+)
+
+// runAssets is the `wynn_bot assets` subcommand, it rebuilds the rank badges
+// and banner patterns from local sources and checks nothing is missing.
+func runAssets(args []string) int {
+	cfg := assets.DefaultConfig
+
+	fs := flag.NewFlagSet("assets", flag.ContinueOnError)
+	fs.StringVar(&cfg.RanksSource, "ranks-src", cfg.RanksSource, "directory of rank badge svgs or pngs")
+	fs.StringVar(&cfg.RanksOutput, "ranks-out", cfg.RanksOutput, "directory for upscaled rank badges")
+	fs.StringVar(&cfg.BannersSource, "banners-src", cfg.BannersSource, "directory of banner pattern svgs or pngs")
+	fs.StringVar(&cfg.BannersOutput, "banners-out", cfg.BannersOutput, "directory for banner pattern pngs")
+	fs.IntVar(&cfg.Scale, "scale", cfg.Scale, "rank badge upscale factor")
+	verifyOnly := fs.Bool("verify", false, "only check that every asset exists")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if !*verifyOnly {
+		if err := assets.Build(cfg); err != nil {
+			log.Printf("building assets failed: %s", err)
+			return 1
+		}
+	}
+
+	missing := assets.Verify(cfg)
+	for _, path := range missing {
+		fmt.Println("missing:", path)
+	}
+	if len(missing) > 0 {
+		return 1
+	}
+
+	fmt.Println("all assets present")
+	return 0
+}
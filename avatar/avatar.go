@@ -0,0 +1,121 @@
+// Package avatar gets the full-body player render drawn on the stats card,
+// trying several sources so a single service being down doesn't fail the card.
+package avatar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/png"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Width and Height are the size of an nmsr full-body render, the slot the
+// stats card scales avatars for.
+const Width, Height = 512, 869
+
+// Player identifies whose avatar to draw, providers use whichever they need.
+type Player struct {
+	Username string
+	UUID     string
+}
+
+// Provider fetches or draws a full-body image of a player.
+type Provider interface {
+	Avatar(ctx context.Context, p Player) (image.Image, error)
+}
+
+// ProviderFunc lets a function be used as a Provider.
+type ProviderFunc func(ctx context.Context, p Player) (image.Image, error)
+
+func (f ProviderFunc) Avatar(ctx context.Context, p Player) (image.Image, error) {
+	return f(ctx, p)
+}
+
+// NMSR renders by username with nmsr.
+type NMSR struct {
+	BaseURL string
+}
+
+func (n NMSR) Avatar(ctx context.Context, p Player) (image.Image, error) {
+	if p.Username == "" {
+		return nil, errors.New("nmsr needs a username")
+	}
+	return fetchImage(ctx, fmt.Sprintf("%s/fullbody/%s", n.BaseURL, url.PathEscape(p.Username)))
+}
+
+// Visage renders by uuid, so it still works right after a name change.
+type Visage struct {
+	BaseURL string
+}
+
+func (v Visage) Avatar(ctx context.Context, p Player) (image.Image, error) {
+	if p.UUID == "" {
+		return nil, errors.New("visage needs a uuid")
+	}
+	return fetchImage(ctx, fmt.Sprintf("%s/full/%d/%s", v.BaseURL, Height, url.PathEscape(compactUUID(p.UUID))))
+}
+
+// Chain tries each provider in order and returns the first image, giving up
+// early once ctx ends.
+type Chain []Provider
+
+func (c Chain) Avatar(ctx context.Context, p Player) (image.Image, error) {
+	var errs []error
+	for _, provider := range c {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		img, err := provider.Avatar(ctx, p)
+		if err == nil {
+			return img, nil
+		}
+		log.Printf("avatar provider %T failed for %s: %v", provider, p.Username, err)
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("no avatar provider worked: %w", errors.Join(errs...))
+}
+
+// Default tries the render services first and draws the skin itself if
+// both are down.
+var Default Provider = Chain{
+	NMSR{BaseURL: "https://nmsr.nickac.dev"},
+	Visage{BaseURL: "https://visage.surgeplay.com"},
+	Skins{SessionURL: "https://sessionserver.mojang.com"},
+}
+
+func compactUUID(uuid string) string {
+	return strings.ReplaceAll(uuid, "-", "")
+}
+
+// fetchTimeout bounds one provider, so a hung one leaves time for the next
+const fetchTimeout = 8 * time.Second
+
+func fetchImage(ctx context.Context, url string) (image.Image, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch image: status %s", resp.Status)
+	}
+
+	img, _, err := image.Decode(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+	return img, nil
+}
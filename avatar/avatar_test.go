@@ -0,0 +1,227 @@
+package avatar
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wynn_bot/internal/golden"
+)
+
+var (
+	red  = color.NRGBA{R: 255, A: 255}
+	blue = color.NRGBA{B: 255, A: 255}
+)
+
+// testSkin colours the front of the right arm red and everything else blue.
+func testSkin(height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetNRGBA(x, y, blue)
+		}
+	}
+	for y := 20; y < 32; y++ {
+		for x := 44; x < 48; x++ {
+			img.SetNRGBA(x, y, red)
+		}
+	}
+	return img
+}
+
+func TestChainFallsThrough(t *testing.T) {
+	want := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	var tried []string
+	chain := Chain{
+		ProviderFunc(func(context.Context, Player) (image.Image, error) {
+			tried = append(tried, "first")
+			return nil, errors.New("down")
+		}),
+		ProviderFunc(func(context.Context, Player) (image.Image, error) {
+			tried = append(tried, "second")
+			return want, nil
+		}),
+		ProviderFunc(func(context.Context, Player) (image.Image, error) {
+			tried = append(tried, "third")
+			return nil, nil
+		}),
+	}
+
+	got, err := chain.Avatar(context.Background(), Player{Username: "Salted"})
+	if err != nil || got != want {
+		t.Fatalf("Avatar() = %v, %v, want the second provider's image", got, err)
+	}
+	if fmt.Sprint(tried) != "[first second]" {
+		t.Errorf("tried %v, want [first second]", tried)
+	}
+
+	if _, err := (Chain{}).Avatar(context.Background(), Player{}); err == nil {
+		t.Error("an empty chain should fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tried = nil
+	if _, err := chain.Avatar(ctx, Player{}); !errors.Is(err, context.Canceled) || len(tried) > 0 {
+		t.Errorf("a cancelled chain returned %v after trying %v", err, tried)
+	}
+}
+
+func TestParseLegacySkin(t *testing.T) {
+	skin, err := ParseSkin(testSkin(32), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the left arm's front is the right arm's front mirrored
+	for y := 52; y < 64; y++ {
+		for x := 36; x < 40; x++ {
+			if got := skin.Texture.NRGBAAt(x, y); got != red {
+				t.Fatalf("left arm front at %d,%d = %v, want red", x, y, got)
+			}
+		}
+	}
+
+	if _, err := ParseSkin(image.NewNRGBA(image.Rect(0, 0, 32, 32)), false); err == nil {
+		t.Error("ParseSkin should reject a 32x32 image")
+	}
+}
+
+func TestSkinsProvider(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/session/minecraft/profile/4b2a0e7c6c1f4f439a531d2f3e4a5b6c", func(w http.ResponseWriter, r *http.Request) {
+		textures := fmt.Sprintf(`{"textures":{"SKIN":{"url":"%s/skin.png","metadata":{"model":"slim"}}}}`, server.URL)
+		fmt.Fprintf(w, `{"properties":[{"name":"textures","value":%q}]}`, base64.StdEncoding.EncodeToString([]byte(textures)))
+	})
+	mux.HandleFunc("/skin.png", func(w http.ResponseWriter, r *http.Request) {
+		png.Encode(w, testSkin(64))
+	})
+
+	skins := Skins{SessionURL: server.URL}
+	skin, err := skins.Skin(context.Background(), "4b2a0e7c-6c1f-4f43-9a53-1d2f3e4a5b6c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !skin.Slim {
+		t.Error("the slim model wasn't picked up")
+	}
+
+	img, err := skins.Avatar(context.Background(), Player{UUID: "4b2a0e7c-6c1f-4f43-9a53-1d2f3e4a5b6c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(Width, Height) {
+		t.Errorf("avatar is %v, want %dx%d", size, Width, Height)
+	}
+
+	if _, err := skins.Avatar(context.Background(), Player{UUID: "00000000000000000000000000000000"}); err == nil {
+		t.Error("an unknown uuid should fail")
+	}
+}
+
+// paintedSkin gives every face of every box its own shade with a checker
+// so the renders show which face went where, plus eyes and a hat brim.
+func paintedSkin(height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, height))
+	boxes := []struct {
+		u, v, w, h, d int
+		c             color.NRGBA
+	}{
+		{0, 0, 8, 8, 8, color.NRGBA{R: 200, G: 150, B: 110, A: 255}},   // head
+		{16, 16, 8, 12, 4, color.NRGBA{R: 80, G: 170, B: 190, A: 255}}, // body
+		{40, 16, 4, 12, 4, color.NRGBA{R: 200, G: 150, B: 110, A: 255}},
+		{0, 16, 4, 12, 4, color.NRGBA{R: 100, G: 100, B: 200, A: 255}},
+		{32, 48, 4, 12, 4, color.NRGBA{R: 190, G: 140, B: 100, A: 255}},
+		{16, 48, 4, 12, 4, color.NRGBA{R: 90, G: 90, B: 190, A: 255}},
+	}
+	for _, b := range boxes {
+		if b.v+b.d+b.h > height {
+			continue
+		}
+		faces := []image.Rectangle{
+			image.Rect(b.u+b.d, b.v, b.u+b.d+b.w, b.v+b.d),               // top
+			image.Rect(b.u+b.d+b.w, b.v, b.u+b.d+2*b.w, b.v+b.d),         // bottom
+			image.Rect(b.u, b.v+b.d, b.u+b.d, b.v+b.d+b.h),               // right
+			image.Rect(b.u+b.d, b.v+b.d, b.u+b.d+b.w, b.v+b.d+b.h),       // front
+			image.Rect(b.u+b.d+b.w, b.v+b.d, b.u+2*b.d+b.w, b.v+b.d+b.h), // left
+			image.Rect(b.u+2*b.d+b.w, b.v+b.d, b.u+2*b.d+2*b.w, b.v+b.d+b.h),
+		}
+		for i, r := range faces {
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					c := b.c
+					shift := uint8(i*12 + (x+y)%2*10)
+					c.R, c.G, c.B = c.R-shift, c.G-shift, c.B-shift
+					img.SetNRGBA(x, y, c)
+				}
+			}
+		}
+	}
+	// eyes and a band around the hat layer
+	img.SetNRGBA(9, 12, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	img.SetNRGBA(10, 12, color.NRGBA{R: 60, G: 40, B: 140, A: 255})
+	img.SetNRGBA(13, 12, color.NRGBA{R: 60, G: 40, B: 140, A: 255})
+	img.SetNRGBA(14, 12, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	for x := 32; x < 64; x++ {
+		img.SetNRGBA(x, 9, color.NRGBA{R: 150, G: 20, B: 30, A: 255})
+	}
+	return img
+}
+
+func TestRender(t *testing.T) {
+	classic, _ := ParseSkin(paintedSkin(64), false)
+	slim, _ := ParseSkin(paintedSkin(64), true)
+	legacy, err := ParseSkin(paintedSkin(32), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bust := DefaultRender
+	bust.View = Bust
+	front := RenderOptions{}
+
+	tests := []struct {
+		name string
+		skin *Skin
+		opts RenderOptions
+	}{
+		{"classic", classic, DefaultRender},
+		{"slim", slim, DefaultRender},
+		{"legacy", legacy, DefaultRender},
+		{"bust", classic, bust},
+		{"front", classic, front},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := tt.skin.Render(tt.opts)
+			if size := img.Bounds().Size(); size != image.Pt(Width, Height) {
+				t.Fatalf("render is %v, want %dx%d", size, Width, Height)
+			}
+			golden.Assert(t, "render_"+tt.name, img, golden.Default)
+		})
+	}
+}
+
+// Renders share buffers, none of one may show up in the next.
+func TestRenderReusesBuffers(t *testing.T) {
+	skin, _ := ParseSkin(paintedSkin(64), false)
+	first := skin.Render(DefaultRender).(*image.NRGBA)
+	skin.Render(RenderOptions{})
+	again := skin.Render(DefaultRender).(*image.NRGBA)
+	if string(first.Pix) != string(again.Pix) {
+		t.Error("the same skin rendered differently after another render")
+	}
+
+	if Silhouette() != Silhouette() {
+		t.Error("the silhouette is drawn again every time")
+	}
+}
@@ -0,0 +1,341 @@
+package avatar
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+)
+
+// View picks how much of the player is drawn.
+type View int
+
+const (
+	FullBody View = iota
+	Bust          // head, body and arms
+)
+
+// Pose rotates the head and swings the limbs, all in degrees. Positive
+// swings move an arm or leg forward, positive yaw turns the head to the
+// player's left and positive pitch tilts it down.
+type Pose struct {
+	HeadYaw, HeadPitch float64
+	RightArm, LeftArm  float64
+	RightLeg, LeftLeg  float64
+}
+
+// RenderOptions controls Render, the zero value is a straight on full body
+// without a pose.
+type RenderOptions struct {
+	View View
+	Pose Pose
+	// Yaw turns the camera around the player and Pitch raises it, in degrees.
+	Yaw, Pitch  float64
+	HideOverlay bool
+}
+
+// DefaultRender is a mid-stride three-quarter view like nmsr's.
+var DefaultRender = RenderOptions{
+	Pose:  Pose{HeadYaw: -8, HeadPitch: 4, RightArm: 18, LeftArm: -18, RightLeg: -14, LeftLeg: 14},
+	Yaw:   -28,
+	Pitch: 12,
+}
+
+// supersample is how many pixels each output pixel averages per side.
+const supersample = 3
+
+type vec struct{ x, y, z float64 }
+
+func (a vec) add(b vec) vec       { return vec{a.x + b.x, a.y + b.y, a.z + b.z} }
+func (a vec) sub(b vec) vec       { return vec{a.x - b.x, a.y - b.y, a.z - b.z} }
+func (a vec) dot(b vec) float64   { return a.x*b.x + a.y*b.y + a.z*b.z }
+func (a vec) scale(s float64) vec { return vec{a.x * s, a.y * s, a.z * s} }
+func (a vec) cross(b vec) vec {
+	return vec{a.y*b.z - a.z*b.y, a.z*b.x - a.x*b.z, a.x*b.y - a.y*b.x}
+}
+
+// rotation is a 3x3 matrix.
+type rotation [3][3]float64
+
+func (r rotation) apply(v vec) vec {
+	return vec{
+		r[0][0]*v.x + r[0][1]*v.y + r[0][2]*v.z,
+		r[1][0]*v.x + r[1][1]*v.y + r[1][2]*v.z,
+		r[2][0]*v.x + r[2][1]*v.y + r[2][2]*v.z,
+	}
+}
+
+func (r rotation) then(next rotation) rotation {
+	var out rotation
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				out[i][j] += next[i][k] * r[k][j]
+			}
+		}
+	}
+	return out
+}
+
+func rotateX(deg float64) rotation {
+	s, c := math.Sincos(deg * math.Pi / 180)
+	return rotation{{1, 0, 0}, {0, c, -s}, {0, s, c}}
+}
+
+func rotateY(deg float64) rotation {
+	s, c := math.Sincos(deg * math.Pi / 180)
+	return rotation{{c, 0, s}, {0, 1, 0}, {-s, 0, c}}
+}
+
+// box is one cuboid of the player model in texels, x to the player's left,
+// y up from the feet and z forward.
+type box struct {
+	min, max vec
+	u, v     int // top left of the box's texture
+	w, h, d  int // texture size of the faces
+	pivot    vec
+	turn     rotation
+}
+
+// face is a quad with the corners where its texture's top left, top right
+// and bottom left go.
+type face struct {
+	a, b, c        vec
+	tu, tv, tw, th int
+}
+
+func (b box) faces() []face {
+	x0, y0, z0 := b.min.x, b.min.y, b.min.z
+	x1, y1, z1 := b.max.x, b.max.y, b.max.z
+	u, v, w, h, d := b.u, b.v, b.w, b.h, b.d
+	return []face{
+		{vec{x0, y1, z1}, vec{x1, y1, z1}, vec{x0, y0, z1}, u + d, v + d, w, h},       // front
+		{vec{x1, y1, z0}, vec{x0, y1, z0}, vec{x1, y0, z0}, u + 2*d + w, v + d, w, h}, // back
+		{vec{x0, y1, z0}, vec{x0, y1, z1}, vec{x0, y0, z0}, u, v + d, d, h},           // right side
+		{vec{x1, y1, z1}, vec{x1, y1, z0}, vec{x1, y0, z1}, u + d + w, v + d, d, h},   // left side
+		{vec{x0, y1, z0}, vec{x1, y1, z0}, vec{x0, y1, z1}, u + d, v, w, d},           // top
+		{vec{x0, y0, z1}, vec{x1, y0, z1}, vec{x0, y0, z0}, u + d + w, v, w, d},       // bottom
+	}
+}
+
+// inflate grows the box by e on every side, for the overlay layer.
+func (b box) inflate(e float64) box {
+	b.min = b.min.sub(vec{e, e, e})
+	b.max = b.max.add(vec{e, e, e})
+	return b
+}
+
+// model builds the boxes for a pose, base layer first and overlay second.
+func model(slim bool, view View, pose Pose) (base, overlay []box) {
+	arm := 4
+	if slim {
+		arm = 3
+	}
+	a := float64(arm)
+
+	head := box{min: vec{-4, 24, -4}, max: vec{4, 32, 4}, u: 0, v: 0, w: 8, h: 8, d: 8,
+		pivot: vec{0, 24, 0}, turn: rotateX(pose.HeadPitch).then(rotateY(pose.HeadYaw))}
+	body := box{min: vec{-4, 12, -2}, max: vec{4, 24, 2}, u: 16, v: 16, w: 8, h: 12, d: 4, turn: rotateX(0)}
+	rightArm := box{min: vec{-4 - a, 12, -2}, max: vec{-4, 24, 2}, u: 40, v: 16, w: arm, h: 12, d: 4,
+		pivot: vec{-4 - a/2, 22, 0}, turn: rotateX(-pose.RightArm)}
+	leftArm := box{min: vec{4, 12, -2}, max: vec{4 + a, 24, 2}, u: 32, v: 48, w: arm, h: 12, d: 4,
+		pivot: vec{4 + a/2, 22, 0}, turn: rotateX(-pose.LeftArm)}
+	rightLeg := box{min: vec{-4, 0, -2}, max: vec{0, 12, 2}, u: 0, v: 16, w: 4, h: 12, d: 4,
+		pivot: vec{-2, 12, 0}, turn: rotateX(-pose.RightLeg)}
+	leftLeg := box{min: vec{0, 0, -2}, max: vec{4, 12, 2}, u: 16, v: 48, w: 4, h: 12, d: 4,
+		pivot: vec{2, 12, 0}, turn: rotateX(-pose.LeftLeg)}
+
+	// the overlay uses the same boxes with its texture 16 or 32 rows down
+	layer := func(b box, u, v int, e float64) box {
+		b = b.inflate(e)
+		b.u, b.v = u, v
+		return b
+	}
+	base = []box{head, body, rightArm, leftArm}
+	overlay = []box{
+		layer(head, 32, 0, 0.5),
+		layer(body, 16, 32, 0.25),
+		layer(rightArm, 40, 32, 0.25),
+		layer(leftArm, 48, 48, 0.25),
+	}
+	if view == FullBody {
+		base = append(base, rightLeg, leftLeg)
+		overlay = append(overlay, layer(rightLeg, 0, 32, 0.25), layer(leftLeg, 0, 48, 0.25))
+	}
+	return base, overlay
+}
+
+// light is the direction faces are lit from, fixed to the player so turning
+// the camera doesn't change the shading.
+var light = func() vec {
+	l := vec{0.35, 1, 0.6}
+	return l.scale(1 / math.Sqrt(l.dot(l)))
+}()
+
+// projected is a face after posing and the camera, with its shade.
+type projected struct {
+	a, b, c vec // screen x, y and depth toward the camera
+	shade   float64
+	face    face
+}
+
+// Render draws the skin at the avatar slot's size, Width by Height, fitted
+// and centred.
+func (s *Skin) Render(opts RenderOptions) image.Image {
+	base, overlay := model(s.Slim, opts.View, opts.Pose)
+	if opts.HideOverlay {
+		overlay = nil
+	}
+	camera := rotateY(opts.Yaw).then(rotateX(opts.Pitch))
+
+	var faces []projected
+	for _, b := range append(base, overlay...) {
+		for _, f := range b.faces() {
+			pose := func(p vec) vec { return b.turn.apply(p.sub(b.pivot)).add(b.pivot) }
+			a, bb, c := pose(f.a), pose(f.b), pose(f.c)
+			normal := c.sub(a).cross(bb.sub(a))
+			if l := math.Sqrt(normal.dot(normal)); l > 0 {
+				normal = normal.scale(1 / l)
+			}
+
+			view := func(p vec) vec {
+				p = camera.apply(p)
+				return vec{p.x, -p.y, p.z}
+			}
+			if camera.apply(normal).z <= 0 {
+				continue // facing away
+			}
+			faces = append(faces, projected{
+				a: view(a), b: view(bb), c: view(c),
+				shade: 0.55 + 0.45*max(0, normal.dot(light)),
+				face:  f,
+			})
+		}
+	}
+
+	// fit the model's outline into the slot with a small margin
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, f := range faces {
+		for _, p := range []vec{f.a, f.b, f.c, f.b.add(f.c).sub(f.a)} {
+			minX, maxX = min(minX, p.x), max(maxX, p.x)
+			minY, maxY = min(minY, p.y), max(maxY, p.y)
+		}
+	}
+	const margin = 0.04
+	scale := min(Width*(1-2*margin)/(maxX-minX), Height*(1-2*margin)/(maxY-minY)) * supersample
+	offsetX := float64(Width*supersample)/2 - (minX+maxX)/2*scale
+	offsetY := float64(Height*supersample)/2 - (minY+maxY)/2*scale
+
+	canvas := rasters.Get().(*raster)
+	defer rasters.Put(canvas)
+	canvas.reset()
+	for _, f := range faces {
+		screen := func(p vec) vec { return vec{p.x*scale + offsetX, p.y*scale + offsetY, p.z} }
+		canvas.fill(screen(f.a), screen(f.b), screen(f.c), func(u, v float64) (color.NRGBA, bool) {
+			tx := f.face.tu + min(int(u*float64(f.face.tw)), f.face.tw-1)
+			ty := f.face.tv + min(int(v*float64(f.face.th)), f.face.th-1)
+			c := s.Texture.NRGBAAt(tx, ty)
+			if c.A < 128 {
+				return c, false
+			}
+			shade := func(x uint8) uint8 { return uint8(math.Round(float64(x) * f.shade)) }
+			return color.NRGBA{R: shade(c.R), G: shade(c.G), B: shade(c.B), A: 255}, true
+		})
+	}
+	return canvas.downsample(supersample)
+}
+
+// raster is a colour buffer with depth for drawing faces in any order.
+type raster struct {
+	img   *image.NRGBA
+	depth []float64
+}
+
+func newRaster(w, h int) *raster {
+	r := &raster{img: image.NewNRGBA(image.Rect(0, 0, w, h)), depth: make([]float64, w*h)}
+	r.reset()
+	return r
+}
+
+// rasters are the supersampled buffers Render draws into, big enough that
+// allocating one per avatar shows, so they're kept for the next.
+var rasters = sync.Pool{New: func() any {
+	return newRaster(Width*supersample, Height*supersample)
+}}
+
+// reset clears r to transparent and infinitely far away.
+func (r *raster) reset() {
+	clear(r.img.Pix)
+	for i := range r.depth {
+		r.depth[i] = math.Inf(-1)
+	}
+}
+
+// fill draws the parallelogram a, b, c, b+c-a, asking texel for the colour
+// at each covered pixel's position along a→b and a→c.
+func (r *raster) fill(a, b, c vec, texel func(u, v float64) (color.NRGBA, bool)) {
+	ab, ac := b.sub(a), c.sub(a)
+	det := ab.x*ac.y - ab.y*ac.x
+	if math.Abs(det) < 1e-9 {
+		return // edge on
+	}
+	d := b.add(ac)
+
+	bounds := r.img.Bounds()
+	x0 := max(bounds.Min.X, int(math.Floor(min(a.x, b.x, c.x, d.x))))
+	x1 := min(bounds.Max.X, int(math.Ceil(max(a.x, b.x, c.x, d.x))))
+	y0 := max(bounds.Min.Y, int(math.Floor(min(a.y, b.y, c.y, d.y))))
+	y1 := min(bounds.Max.Y, int(math.Ceil(max(a.y, b.y, c.y, d.y))))
+
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			px, py := float64(x)+0.5-a.x, float64(y)+0.5-a.y
+			u := (px*ac.y - py*ac.x) / det
+			v := (py*ab.x - px*ab.y) / det
+			if u < 0 || u >= 1 || v < 0 || v >= 1 {
+				continue
+			}
+			z := a.z + u*ab.z + v*ac.z
+			i := y*bounds.Dx() + x
+			if z <= r.depth[i] {
+				continue
+			}
+			if c, ok := texel(u, v); ok {
+				r.depth[i] = z
+				r.img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// downsample averages n by n blocks, weighting colour by alpha.
+func (r *raster) downsample(n int) *image.NRGBA {
+	src := r.img
+	out := image.NewNRGBA(image.Rect(0, 0, src.Bounds().Dx()/n, src.Bounds().Dy()/n))
+	for y := 0; y < out.Bounds().Dy(); y++ {
+		for x := 0; x < out.Bounds().Dx(); x++ {
+			var rs, gs, bs, as float64
+			for sy := 0; sy < n; sy++ {
+				for sx := 0; sx < n; sx++ {
+					c := src.NRGBAAt(x*n+sx, y*n+sy)
+					alpha := float64(c.A)
+					rs += float64(c.R) * alpha
+					gs += float64(c.G) * alpha
+					bs += float64(c.B) * alpha
+					as += alpha
+				}
+			}
+			if as == 0 {
+				continue
+			}
+			out.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(math.Round(rs / as)),
+				G: uint8(math.Round(gs / as)),
+				B: uint8(math.Round(bs / as)),
+				A: uint8(math.Round(as / float64(n*n))),
+			})
+		}
+	}
+	return out
+}
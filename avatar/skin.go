@@ -0,0 +1,164 @@
+package avatar
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Skin is a skin texture in the 64x64 layout.
+type Skin struct {
+	Texture *image.NRGBA
+	Slim    bool // the 3 pixel wide arms of the Alex model
+}
+
+// ParseSkin reads a 64x64 skin or a legacy 64x32 one, which gets its left
+// arm and leg mirrored from the right ones like the game does.
+func ParseSkin(img image.Image, slim bool) (*Skin, error) {
+	size := img.Bounds().Size()
+	if size.X != 64 || (size.Y != 64 && size.Y != 32) {
+		return nil, fmt.Errorf("unsupported skin size %dx%d", size.X, size.Y)
+	}
+
+	texture := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(texture, image.Rect(0, 0, 64, size.Y), img, img.Bounds().Min, draw.Src)
+	if size.Y == 32 {
+		mirrorLimb(texture, image.Pt(0, 16), image.Pt(16, 48))  // leg
+		mirrorLimb(texture, image.Pt(40, 16), image.Pt(32, 48)) // arm
+	}
+	return &Skin{Texture: texture, Slim: slim}, nil
+}
+
+// mirrorLimb copies the 4x12x4 limb whose texture starts at from to to,
+// flipping each face and swapping the outer and inner sides.
+func mirrorLimb(texture *image.NRGBA, from, to image.Point) {
+	faces := []struct{ src, dst, size image.Point }{
+		{image.Pt(4, 0), image.Pt(4, 0), image.Pt(4, 4)},    // top
+		{image.Pt(8, 0), image.Pt(8, 0), image.Pt(4, 4)},    // bottom
+		{image.Pt(0, 4), image.Pt(8, 4), image.Pt(4, 12)},   // outer side becomes the inner
+		{image.Pt(4, 4), image.Pt(4, 4), image.Pt(4, 12)},   // front
+		{image.Pt(8, 4), image.Pt(0, 4), image.Pt(4, 12)},   // inner side becomes the outer
+		{image.Pt(12, 4), image.Pt(12, 4), image.Pt(4, 12)}, // back
+	}
+	for _, f := range faces {
+		for y := 0; y < f.size.Y; y++ {
+			for x := 0; x < f.size.X; x++ {
+				c := texture.NRGBAAt(from.X+f.src.X+x, from.Y+f.src.Y+y)
+				texture.SetNRGBA(to.X+f.dst.X+f.size.X-1-x, to.Y+f.dst.Y+y, c)
+			}
+		}
+	}
+}
+
+// Silhouette is drawn when no provider has an avatar: the default pose in
+// translucent grey. It's rendered once and shared, so draw it, don't draw
+// on it.
+func Silhouette() image.Image {
+	return silhouette()
+}
+
+var silhouette = sync.OnceValue(func() *image.NRGBA {
+	texture := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(texture, texture.Bounds(), image.NewUniform(color.NRGBA{R: 170, G: 170, B: 180, A: 255}), image.Point{}, draw.Src)
+	opts := DefaultRender
+	opts.HideOverlay = true
+	img := (&Skin{Texture: texture}).Render(opts).(*image.NRGBA)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = uint8(int(img.Pix[i]) * 110 / 255)
+	}
+	return img
+})
+
+// Skins draws the avatar from the player's skin texture, looked up by uuid
+// on the session server, so it only depends on Mojang being up.
+type Skins struct {
+	SessionURL string
+}
+
+func (s Skins) Avatar(ctx context.Context, p Player) (image.Image, error) {
+	skin, err := s.Skin(ctx, p.UUID)
+	if err != nil {
+		return nil, err
+	}
+	return skin.Render(DefaultRender), nil
+}
+
+// Skin fetches and parses a player's current skin.
+func (s Skins) Skin(ctx context.Context, uuid string) (*Skin, error) {
+	if uuid == "" {
+		return nil, errors.New("skins need a uuid")
+	}
+
+	var profile struct {
+		Properties []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"properties"`
+	}
+	if err := getJSON(ctx, fmt.Sprintf("%s/session/minecraft/profile/%s", s.SessionURL, url.PathEscape(compactUUID(uuid))), &profile); err != nil {
+		return nil, err
+	}
+
+	for _, prop := range profile.Properties {
+		if prop.Name != "textures" {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(prop.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode textures: %v", err)
+		}
+		var textures struct {
+			Textures struct {
+				Skin struct {
+					URL      string `json:"url"`
+					Metadata struct {
+						Model string `json:"model"`
+					} `json:"metadata"`
+				} `json:"SKIN"`
+			} `json:"textures"`
+		}
+		if err := json.Unmarshal(raw, &textures); err != nil {
+			return nil, fmt.Errorf("failed to decode textures: %v", err)
+		}
+		if textures.Textures.Skin.URL == "" {
+			return nil, errors.New("player has no skin set")
+		}
+
+		img, err := fetchImage(ctx, textures.Textures.Skin.URL)
+		if err != nil {
+			return nil, err
+		}
+		return ParseSkin(img, textures.Textures.Skin.Metadata.Model == "slim")
+	}
+	return nil, errors.New("profile has no textures")
+}
+
+func getJSON(ctx context.Context, url string, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch profile: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch profile: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch profile: status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode profile: %v", err)
+	}
+	return nil
+}
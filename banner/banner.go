@@ -0,0 +1,153 @@
+package banner
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"wynn_bot/assets"
+	"wynn_bot/models"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Width and Height are the size the pattern images are drawn at.
+const Width, Height = 160, 320
+
+// Palette maps the api's dye colour names to the colour drawn.
+type Palette map[string]color.RGBA
+
+var Colors = Palette{
+	"BLACK":      {R: 21, G: 21, B: 24, A: 255},
+	"RED":        {R: 129, G: 34, B: 28, A: 255},
+	"GREEN":      {R: 69, G: 91, B: 16, A: 255},
+	"BROWN":      {R: 96, G: 62, B: 37, A: 255},
+	"BLUE":       {R: 43, G: 49, B: 123, A: 255},
+	"PURPLE":     {R: 100, G: 37, B: 135, A: 255},
+	"CYAN":       {R: 16, G: 114, B: 114, A: 255},
+	"SILVER":     {R: 115, G: 115, B: 111, A: 255},
+	"GRAY":       {R: 52, G: 57, B: 60, A: 255},
+	"PINK":       {R: 179, G: 102, B: 125, A: 255},
+	"LIME":       {R: 93, G: 145, B: 22, A: 255},
+	"YELLOW":     {R: 186, G: 158, B: 45, A: 255},
+	"LIGHT_BLUE": {R: 42, G: 131, B: 160, A: 255},
+	"MAGENTA":    {R: 145, G: 57, B: 138, A: 255},
+	"ORANGE":     {R: 180, G: 93, B: 21, A: 255},
+	"WHITE":      {R: 182, G: 187, B: 186, A: 255},
+}
+
+// Darkened is what the stats card uses so text stays readable on top.
+var Darkened = Colors.Darken(0.5)
+
+// Darken returns a copy of the palette with every colour scaled by factor.
+func (p Palette) Darken(factor float64) Palette {
+	darkened := make(Palette, len(p))
+	for name, col := range p {
+		darkened[name] = color.RGBA{
+			R: uint8(float64(col.R) * factor),
+			G: uint8(float64(col.G) * factor),
+			B: uint8(float64(col.B) * factor),
+			A: col.A, // Keep alpha unchanged
+		}
+	}
+	return darkened
+}
+
+const fallbackColour = "SILVER"
+
+type maskKey struct {
+	pattern string
+	w, h    int
+}
+
+// Renderer draws banners from the pattern images in Dir. Pattern masks are
+// loaded and scaled once per size and shared between renders.
+type Renderer struct {
+	Dir  string
+	Font string // for poster captions
+
+	mu    sync.Mutex
+	masks map[maskKey]*image.Alpha
+}
+
+func NewRenderer(dir, font string) *Renderer {
+	return &Renderer{Dir: dir, Font: font, masks: make(map[maskKey]*image.Alpha)}
+}
+
+// Default reads the bundled patterns.
+var Default = NewRenderer("statscard/banner", "statscard/fonts/minecraft.ttf")
+
+// Render draws b at w x h. Unknown colours fall back to silver and unknown
+// patterns are skipped with a warning, so a banner always comes out.
+func (r *Renderer) Render(b models.Banner, w, h int, palette Palette) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(colour(palette, b.Base)), image.Point{}, draw.Src)
+
+	for _, layer := range b.Layers {
+		mask, err := r.mask(layer.Pattern, w, h)
+		if err != nil {
+			log.Printf("skipping banner layer: %v", err)
+			continue
+		}
+		draw.DrawMask(img, img.Bounds(), image.NewUniform(colour(palette, layer.Colour)), image.Point{}, mask, image.Point{}, draw.Over)
+	}
+	return img
+}
+
+func colour(palette Palette, name string) color.RGBA {
+	if c, ok := palette[name]; ok {
+		return c
+	}
+	log.Printf("unknown banner colour %q, using %s", name, fallbackColour)
+	return palette[fallbackColour]
+}
+
+// mask returns the pattern's alpha channel scaled to w x h, the pattern
+// colour comes from the uniform source it's drawn with.
+func (r *Renderer) mask(pattern string, w, h int) (*image.Alpha, error) {
+	key := maskKey{pattern, w, h}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if mask, ok := r.masks[key]; ok {
+		return mask, nil
+	}
+
+	src, err := loadPattern(filepath.Join(r.Dir, assets.BannerFile(pattern)))
+	if err != nil {
+		return nil, fmt.Errorf("pattern %s: %v", pattern, err)
+	}
+
+	alpha := image.NewAlpha(src.Bounds())
+	draw.Draw(alpha, alpha.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	mask := alpha
+	if alpha.Rect.Dx() != w || alpha.Rect.Dy() != h {
+		mask = image.NewAlpha(image.Rect(0, 0, w, h))
+		xdraw.BiLinear.Scale(mask, mask.Bounds(), alpha, alpha.Bounds(), draw.Src, nil)
+	}
+
+	r.masks[key] = mask
+	return mask, nil
+}
+
+func loadPattern(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pattern: %v", err)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pattern: %v", err)
+	}
+	return img, nil
+}
@@ -0,0 +1,62 @@
+package banner
+
+import (
+	"testing"
+
+	"wynn_bot/internal/golden"
+	"wynn_bot/models"
+)
+
+func testRenderer() *Renderer {
+	return NewRenderer("../statscard/banner", "../statscard/fonts/minecraft.ttf")
+}
+
+var testBanner = models.Banner{
+	Base:      "BLUE",
+	Structure: "pole",
+	Layers: []models.BannerLayer{
+		{Colour: "WHITE", Pattern: "STRIPE_CENTER"},
+		{Colour: "YELLOW", Pattern: "CIRCLE_MIDDLE"},
+		{Colour: "BLACK", Pattern: "BORDER"},
+	},
+}
+
+func TestRenderTiers(t *testing.T) {
+	r := testRenderer()
+	for _, tier := range []int{0, 1, 3, 6} {
+		b := testBanner
+		b.Tier = tier
+		t.Run(Label(b), func(t *testing.T) {
+			golden.Assert(t, "tier"+string(rune('0'+tier)), r.RenderFramed(b, Width, Height, Colors), golden.Default)
+		})
+	}
+}
+
+func TestPoster(t *testing.T) {
+	b := testBanner
+	b.Tier = 4
+	img, err := testRenderer().Poster(models.GuildData{Name: "Sequoia", Prefix: "SEQ", Banner: b}, 1, Darkened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, "poster", img, golden.Default)
+}
+
+func TestUnknownPatternIsSkipped(t *testing.T) {
+	r := testRenderer()
+	withUnknown := testBanner
+	withUnknown.Layers = append([]models.BannerLayer{{Colour: "RED", Pattern: "NOT_A_PATTERN"}}, testBanner.Layers...)
+
+	if _, n := golden.Diff(r.Render(testBanner, Width, Height, Colors), r.Render(withUnknown, Width, Height, Colors), golden.Options{}); n > 0 {
+		t.Error("an unknown pattern changed the banner")
+	}
+}
+
+func TestFrameForClampsTier(t *testing.T) {
+	if _, ok := FrameFor(0); ok {
+		t.Error("tier 0 should have no frame")
+	}
+	if frame, _ := FrameFor(99); frame.Name != Frames[len(Frames)-1].Name {
+		t.Errorf("tier 99 got the %s frame, want the last one", frame.Name)
+	}
+}
@@ -0,0 +1,137 @@
+package banner
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	"wynn_bot/models"
+
+	"github.com/fogleman/gg"
+)
+
+// Frame is the border a banner of one tier is drawn with. Sizes are in
+// pattern pixels and scale with the banner.
+type Frame struct {
+	Name      string
+	Outer     color.RGBA
+	Inner     color.RGBA // highlight just inside the outer border
+	Thickness float64
+	Studs     bool // square studs on the corners
+}
+
+// Frames are the variants by tier, tiers past the last one use the last one.
+// They're drawn from these few numbers rather than shipped as images, so a
+// frame stays crisp at any banner size and a new tier is one more line here
+// instead of a file per size in the asset manifest.
+var Frames = []Frame{
+	{Name: "wood", Outer: color.RGBA{R: 94, G: 64, B: 38, A: 255}, Inner: color.RGBA{R: 140, G: 100, B: 62, A: 255}, Thickness: 4},
+	{Name: "stone", Outer: color.RGBA{R: 90, G: 90, B: 94, A: 255}, Inner: color.RGBA{R: 140, G: 140, B: 146, A: 255}, Thickness: 5},
+	{Name: "iron", Outer: color.RGBA{R: 150, G: 150, B: 150, A: 255}, Inner: color.RGBA{R: 216, G: 216, B: 216, A: 255}, Thickness: 6},
+	{Name: "gold", Outer: color.RGBA{R: 196, G: 146, B: 30, A: 255}, Inner: color.RGBA{R: 250, G: 220, B: 90, A: 255}, Thickness: 6, Studs: true},
+	{Name: "diamond", Outer: color.RGBA{R: 36, G: 150, B: 160, A: 255}, Inner: color.RGBA{R: 120, G: 236, B: 226, A: 255}, Thickness: 7, Studs: true},
+	{Name: "netherite", Outer: color.RGBA{R: 50, G: 44, B: 48, A: 255}, Inner: color.RGBA{R: 180, G: 120, B: 255, A: 255}, Thickness: 8, Studs: true},
+}
+
+// FrameFor returns the frame for a tier, false for banners without one.
+func FrameFor(tier int) (Frame, bool) {
+	if tier <= 0 {
+		return Frame{}, false
+	}
+	return Frames[min(tier, len(Frames))-1], true
+}
+
+// DrawFrame borders img for the given tier, doing nothing for tier 0.
+func DrawFrame(img draw.Image, tier int) {
+	frame, ok := FrameFor(tier)
+	if !ok {
+		return
+	}
+
+	b := img.Bounds()
+	scale := float64(b.Dx()) / Width
+	outer := max(1, int(math.Round(frame.Thickness*scale)))
+	inner := max(1, outer/3)
+
+	border(img, b, outer, frame.Outer)
+	border(img, b.Inset(outer), inner, frame.Inner)
+
+	if frame.Studs {
+		stud := outer * 2
+		for _, p := range []image.Point{
+			b.Min,
+			{b.Max.X - stud, b.Min.Y},
+			{b.Min.X, b.Max.Y - stud},
+			b.Max.Sub(image.Pt(stud, stud)),
+		} {
+			r := image.Rectangle{Min: p, Max: p.Add(image.Pt(stud, stud))}
+			draw.Draw(img, r, image.NewUniform(frame.Inner), image.Point{}, draw.Src)
+			draw.Draw(img, r.Inset(max(1, stud/4)), image.NewUniform(frame.Outer), image.Point{}, draw.Src)
+		}
+	}
+}
+
+func border(img draw.Image, r image.Rectangle, thickness int, c color.Color) {
+	src := image.NewUniform(c)
+	for _, side := range []image.Rectangle{
+		{Min: r.Min, Max: image.Pt(r.Max.X, r.Min.Y+thickness)},
+		{Min: image.Pt(r.Min.X, r.Max.Y-thickness), Max: r.Max},
+		{Min: r.Min, Max: image.Pt(r.Min.X+thickness, r.Max.Y)},
+		{Min: image.Pt(r.Max.X-thickness, r.Min.Y), Max: r.Max},
+	} {
+		draw.Draw(img, side, src, image.Point{}, draw.Src)
+	}
+}
+
+// Label describes the tier and structure, e.g. "tier 3 gold · pole".
+func Label(b models.Banner) string {
+	var parts []string
+	if frame, ok := FrameFor(b.Tier); ok {
+		parts = append(parts, fmt.Sprintf("tier %d %s", b.Tier, frame.Name))
+	}
+	if b.Structure != "" {
+		parts = append(parts, strings.ToLower(strings.ReplaceAll(b.Structure, "_", " ")))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// RenderFramed draws the banner with its tier frame.
+func (r *Renderer) RenderFramed(b models.Banner, w, h int, palette Palette) *image.RGBA {
+	img := r.Render(b, w, h, palette)
+	DrawFrame(img, b.Tier)
+	return img
+}
+
+// Poster draws the framed banner at scale times the pattern size with the
+// guild name, tier and structure written underneath, for the /banner command.
+func (r *Renderer) Poster(guild models.GuildData, scale int, palette Palette) (image.Image, error) {
+	w, h := Width*scale, Height*scale
+	const padding, caption = 24, 76
+
+	poster := gg.NewContext(w+2*padding, h+2*padding+caption)
+	poster.SetRGB255(24, 22, 28)
+	poster.Clear()
+	poster.DrawImage(r.RenderFramed(guild.Banner, w, h, palette), padding, padding)
+
+	if err := poster.LoadFontFace(r.Font, 26); err != nil {
+		return nil, fmt.Errorf("failed to load font: %v", err)
+	}
+	poster.SetRGB255(255, 255, 255)
+	poster.DrawStringAnchored(fmt.Sprintf("%s [%s]", guild.Name, guild.Prefix), float64(poster.Width())/2, float64(h+padding)+32, 0.5, 0.5)
+
+	if label := Label(guild.Banner); label != "" {
+		if err := poster.LoadFontFace(r.Font, 18); err != nil {
+			return nil, fmt.Errorf("failed to load font: %v", err)
+		}
+		poster.SetRGB255(190, 186, 196)
+		if frame, ok := FrameFor(guild.Banner.Tier); ok {
+			poster.SetColor(frame.Inner)
+		}
+		poster.DrawStringAnchored(label, float64(poster.Width())/2, float64(h+padding)+64, 0.5, 0.5)
+	}
+
+	return poster.Image(), nil
+}
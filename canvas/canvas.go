@@ -0,0 +1,122 @@
+// Package canvas lets the cards draw to more than a bitmap. Canvas is the
+// part of *gg.Context the renderers use, so the same drawing code can fill
+// a gg context for a PNG or an SVG document for the website.
+package canvas
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+
+	"github.com/fogleman/gg"
+)
+
+// Canvas is what the card and chart renderers draw on. *gg.Context is one.
+type Canvas interface {
+	Clear()
+	SetColor(c color.Color)
+	SetHexColor(x string)
+	SetRGB(r, g, b float64)
+	SetLineWidth(lineWidth float64)
+
+	LoadFontFace(path string, points float64) error
+	FontHeight() float64
+	MeasureString(s string) (w, h float64)
+	DrawString(s string, x, y float64)
+	DrawStringAnchored(s string, x, y, ax, ay float64)
+
+	MoveTo(x, y float64)
+	LineTo(x, y float64)
+	ClosePath()
+	DrawLine(x1, y1, x2, y2 float64)
+	DrawRectangle(x, y, w, h float64)
+	DrawRoundedRectangle(x, y, w, h, r float64)
+	DrawCircle(x, y, r float64)
+	DrawArc(x, y, r, angle1, angle2 float64)
+	Fill()
+	Stroke()
+
+	DrawImage(im image.Image, x, y int)
+	DrawImageAnchored(im image.Image, x, y int, ax, ay float64)
+}
+
+var _ Canvas = (*gg.Context)(nil)
+
+// Format is how a drawing is written out.
+type Format string
+
+const (
+	FormatPNG Format = "png"
+	// FormatPNG8 is a PNG reduced to a 256 colour palette, a fraction of the
+	// size for cards that are mostly flat colour.
+	FormatPNG8 Format = "png8"
+	// FormatSVG keeps shapes and text as vectors, so they stay crisp at any size.
+	FormatSVG Format = "svg"
+	// FormatGIF is a still GIF from Encode, see EncodeGIF for animations.
+	FormatGIF Format = "gif"
+)
+
+// Ext is the file extension for f, both PNGs share one.
+func (f Format) Ext() string {
+	switch f {
+	case FormatSVG:
+		return ".svg"
+	case FormatGIF:
+		return ".gif"
+	}
+	return ".png"
+}
+
+// ContentType is the MIME type to serve f as.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatGIF:
+		return "image/gif"
+	}
+	return "image/png"
+}
+
+// Encode draws a width by height picture with draw and writes it to w as f.
+func Encode(w io.Writer, f Format, width, height int, draw func(Canvas) error) error {
+	if f == FormatSVG {
+		doc := NewSVG(width, height)
+		if err := draw(doc); err != nil {
+			return err
+		}
+		_, err := doc.WriteTo(w)
+		return err
+	}
+
+	dc := gg.NewContext(width, height)
+	if err := draw(dc); err != nil {
+		return err
+	}
+	return EncodeImage(w, dc.Image(), f)
+}
+
+// EncodeImage writes an image that is already drawn, which can't become an SVG.
+func EncodeImage(w io.Writer, img image.Image, f Format) error {
+	switch f {
+	case FormatPNG:
+		if err := png.Encode(w, img); err != nil {
+			return fmt.Errorf("failed to encode png: %v", err)
+		}
+	case FormatPNG8:
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := enc.Encode(w, Quantize(img, 256)); err != nil {
+			return fmt.Errorf("failed to encode png: %v", err)
+		}
+	case FormatGIF:
+		if err := gif.Encode(w, Quantize(img, 256), nil); err != nil {
+			return fmt.Errorf("failed to encode gif: %v", err)
+		}
+	default:
+		return fmt.Errorf("can't encode a bitmap as %s", f)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package canvas
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"time"
+
+	"github.com/fogleman/gg"
+)
+
+// GIFOptions shape an animation, the zero value takes the defaults.
+type GIFOptions struct {
+	Frames int           // drawn frames, 30 if zero
+	Delay  time.Duration // between frames, 60ms if zero
+	Hold   time.Duration // added to the last frame so it can be read
+	// MaxBytes bounds the file. Every other frame is dropped, the rest
+	// shown for longer so the loop takes as long, until it fits. Zero for
+	// no bound.
+	MaxBytes int
+}
+
+// EncodeGIF draws a width by height animation with draw, which is told the
+// frame it's drawing out of how many, and writes it as a looping GIF. The
+// frames share one palette, and each after the first stores only what
+// changed, which keeps a card that is mostly still small.
+func EncodeGIF(w io.Writer, width, height int, opts GIFOptions, draw func(c Canvas, frame, frames int) error) error {
+	if opts.Frames <= 0 {
+		opts.Frames = 30
+	}
+	if opts.Delay <= 0 {
+		opts.Delay = 60 * time.Millisecond
+	}
+
+	frames := make([]*image.RGBA, opts.Frames)
+	for i := range frames {
+		dc := gg.NewContext(width, height)
+		if err := draw(dc, i, opts.Frames); err != nil {
+			return err
+		}
+		frames[i] = dc.Image().(*image.RGBA)
+	}
+
+	delay, loop := opts.Delay, opts.Delay*time.Duration(opts.Frames)
+	for {
+		var buf bytes.Buffer
+		if err := encodeFrames(&buf, frames, delay, opts.Hold); err != nil {
+			return err
+		}
+		if opts.MaxBytes == 0 || buf.Len() <= opts.MaxBytes {
+			_, err := w.Write(buf.Bytes())
+			return err
+		}
+		if len(frames) <= 2 {
+			return fmt.Errorf("the gif is %d bytes with %d frames, over the %d allowed", buf.Len(), len(frames), opts.MaxBytes)
+		}
+		frames = everyOther(frames)
+		delay = loop / time.Duration(len(frames))
+	}
+}
+
+// everyOther halves the frames, keeping the last so the animation still
+// ends where it should.
+func everyOther(frames []*image.RGBA) []*image.RGBA {
+	var kept []*image.RGBA
+	for i := 0; i < len(frames)-1; i += 2 {
+		kept = append(kept, frames[i])
+	}
+	return append(kept, frames[len(frames)-1])
+}
+
+func encodeFrames(w io.Writer, frames []*image.RGBA, delay, hold time.Duration) error {
+	bounds := frames[0].Bounds()
+
+	// the first frame whole, then whatever changes, for the palette and
+	// for what each frame needs to store
+	counts := map[color.NRGBA]int{}
+	addColours(counts, frames[0], bounds)
+	changed := make([]image.Rectangle, len(frames))
+	changed[0] = bounds
+	for i := 1; i < len(frames); i++ {
+		changed[i] = diff(frames[i-1], frames[i], func(x, y int) {
+			counts[color.NRGBAModel.Convert(frames[i].At(x, y)).(color.NRGBA)]++
+		})
+	}
+
+	// the last entry is left for the pixels a frame doesn't change, so
+	// nothing drawn maps to it
+	colours := medianCut(counts, 255)
+	pal := append(colours[:len(colours):len(colours)], color.NRGBA{})
+	unchanged := uint8(len(pal) - 1)
+	index := map[color.NRGBA]uint8{}
+	lookup := func(c color.Color) uint8 {
+		nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+		i, ok := index[nc]
+		if !ok {
+			i = uint8(colours.Index(nc))
+			index[nc] = i
+		}
+		return i
+	}
+
+	anim := &gif.GIF{Config: image.Config{ColorModel: pal, Width: bounds.Dx(), Height: bounds.Dy()}}
+	centis := func(d time.Duration) int { return int((d + 5*time.Millisecond) / (10 * time.Millisecond)) }
+	for i, frame := range frames {
+		r := changed[i]
+		if r.Empty() {
+			// nothing moved, show the last frame for longer instead
+			anim.Delay[len(anim.Delay)-1] += centis(delay)
+			continue
+		}
+		img := image.NewPaletted(r, pal)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if i > 0 && frame.RGBAAt(x, y) == frames[i-1].RGBAAt(x, y) {
+					img.SetColorIndex(x, y, unchanged)
+					continue
+				}
+				img.SetColorIndex(x, y, lookup(frame.RGBAAt(x, y)))
+			}
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, centis(delay))
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+	anim.Delay[len(anim.Delay)-1] += centis(hold)
+
+	if err := gif.EncodeAll(w, anim); err != nil {
+		return fmt.Errorf("failed to encode gif: %v", err)
+	}
+	return nil
+}
+
+// diff calls changed for each pixel that differs between a and b and
+// returns the rectangle bounding them.
+func diff(a, b *image.RGBA, changed func(x, y int)) image.Rectangle {
+	var r image.Rectangle
+	bounds := b.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if a.RGBAAt(x, y) != b.RGBAAt(x, y) {
+				changed(x, y)
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,83 @@
+package canvas
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"slices"
+	"testing"
+	"time"
+)
+
+// a square moving along a line, the rest of the picture staying put
+func drawSquare(c Canvas, frame, _ int) error {
+	c.SetColor(color.White)
+	c.Clear()
+	c.SetHexColor("#336699")
+	c.DrawRectangle(float64(10*frame), 20, 10, 10)
+	c.Fill()
+	return nil
+}
+
+func TestEncodeGIF(t *testing.T) {
+	var buf bytes.Buffer
+	opts := GIFOptions{Frames: 4, Delay: 50 * time.Millisecond, Hold: time.Second}
+	if err := EncodeGIF(&buf, 100, 50, opts, drawSquare); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 4 {
+		t.Fatalf("%d frames, want 4", len(anim.Image))
+	}
+	if want := []int{5, 5, 5, 105}; !slices.Equal(anim.Delay, want) {
+		t.Errorf("delays %v, want %v", anim.Delay, want)
+	}
+
+	// later frames only cover where the square moved
+	if got, want := anim.Image[2].Bounds(), image.Rect(10, 20, 30, 30); got != want {
+		t.Errorf("frame 2 covers %v, want %v", got, want)
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for _, frame := range anim.Image {
+		draw.Draw(out, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	}
+	for _, p := range []image.Point{{35, 25}, {5, 25}, {50, 5}} {
+		want := color.RGBA{0x33, 0x66, 0x99, 0xff}
+		if p.X < 30 || p.Y < 20 {
+			want = color.RGBA{0xff, 0xff, 0xff, 0xff}
+		}
+		if got := out.RGBAAt(p.X, p.Y); got != want {
+			t.Errorf("pixel %v is %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestEncodeGIFDropsFrames(t *testing.T) {
+	var full bytes.Buffer
+	if err := EncodeGIF(&full, 100, 50, GIFOptions{Frames: 8}, drawSquare); err != nil {
+		t.Fatal(err)
+	}
+
+	var bounded bytes.Buffer
+	if err := EncodeGIF(&bounded, 100, 50, GIFOptions{Frames: 8, MaxBytes: full.Len() - 1}, drawSquare); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&bounded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every other frame and the last, shown for as long as all 8 were
+	if want := []int{10, 10, 10, 10, 10}; !slices.Equal(anim.Delay, want) {
+		t.Errorf("delays %v, want %v", anim.Delay, want)
+	}
+
+	if err := EncodeGIF(&bounded, 100, 50, GIFOptions{Frames: 8, MaxBytes: 10}, drawSquare); err == nil {
+		t.Error("a 10 byte bound didn't fail")
+	}
+}
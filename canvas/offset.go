@@ -0,0 +1,65 @@
+package canvas
+
+import "image"
+
+// Offset is c moved right by x and down by y, for drawing code that starts
+// at 0,0 to fill a w by h area of a bigger canvas. Clear paints just that
+// area with the current colour, over what's already there, and uses up the
+// current path as Fill does.
+func Offset(c Canvas, x, y, w, h float64) Canvas {
+	return &offset{Canvas: c, x: x, y: y, w: w, h: h}
+}
+
+type offset struct {
+	Canvas
+	x, y, w, h float64
+}
+
+func (o *offset) Clear() {
+	o.Canvas.DrawRectangle(o.x, o.y, o.w, o.h)
+	o.Canvas.Fill()
+}
+
+func (o *offset) DrawString(s string, x, y float64) {
+	o.Canvas.DrawString(s, o.x+x, o.y+y)
+}
+
+func (o *offset) DrawStringAnchored(s string, x, y, ax, ay float64) {
+	o.Canvas.DrawStringAnchored(s, o.x+x, o.y+y, ax, ay)
+}
+
+func (o *offset) MoveTo(x, y float64) {
+	o.Canvas.MoveTo(o.x+x, o.y+y)
+}
+
+func (o *offset) LineTo(x, y float64) {
+	o.Canvas.LineTo(o.x+x, o.y+y)
+}
+
+func (o *offset) DrawLine(x1, y1, x2, y2 float64) {
+	o.Canvas.DrawLine(o.x+x1, o.y+y1, o.x+x2, o.y+y2)
+}
+
+func (o *offset) DrawRectangle(x, y, w, h float64) {
+	o.Canvas.DrawRectangle(o.x+x, o.y+y, w, h)
+}
+
+func (o *offset) DrawRoundedRectangle(x, y, w, h, r float64) {
+	o.Canvas.DrawRoundedRectangle(o.x+x, o.y+y, w, h, r)
+}
+
+func (o *offset) DrawCircle(x, y, r float64) {
+	o.Canvas.DrawCircle(o.x+x, o.y+y, r)
+}
+
+func (o *offset) DrawArc(x, y, r, angle1, angle2 float64) {
+	o.Canvas.DrawArc(o.x+x, o.y+y, r, angle1, angle2)
+}
+
+func (o *offset) DrawImage(im image.Image, x, y int) {
+	o.Canvas.DrawImage(im, int(o.x)+x, int(o.y)+y)
+}
+
+func (o *offset) DrawImageAnchored(im image.Image, x, y int, ax, ay float64) {
+	o.Canvas.DrawImageAnchored(im, int(o.x)+x, int(o.y)+y, ax, ay)
+}
@@ -0,0 +1,47 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/fogleman/gg"
+)
+
+func TestOffset(t *testing.T) {
+	dc := gg.NewContext(20, 20)
+	area := Offset(dc, 10, 5, 6, 4)
+	area.SetRGB(1, 0, 0)
+	area.Clear()
+	area.SetRGB(0, 0, 1)
+	area.DrawRectangle(0, 0, 2, 2)
+	area.Fill()
+
+	img := dc.Image().(*image.RGBA)
+	for _, tt := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{0, 0, color.RGBA{}},                // outside the area, Clear left it alone
+		{10, 5, color.RGBA{B: 255, A: 255}}, // the area's 0,0
+		{13, 7, color.RGBA{R: 255, A: 255}},
+		{16, 9, color.RGBA{}},
+	} {
+		if got := img.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("pixel %d,%d is %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	// the same on an SVG, where the chart stays vectors
+	doc := NewSVG(20, 20)
+	Offset(doc, 10, 5, 6, 4).DrawRectangle(1, 2, 3, 3)
+	doc.Fill()
+	var buf strings.Builder
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := `d="M11 7L14 7L14 10L11 10Z"`; !strings.Contains(buf.String(), want) {
+		t.Errorf("got %s, want the rectangle at %s", buf.String(), want)
+	}
+}
@@ -0,0 +1,129 @@
+package canvas
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"slices"
+)
+
+// Quantize maps img onto at most n colours picked by median cut. An image
+// with n colours or fewer keeps them exactly, so flat cards lose nothing.
+func Quantize(img image.Image, n int) *image.Paletted {
+	b := img.Bounds()
+	counts := map[color.NRGBA]int{}
+	addColours(counts, img, b)
+
+	pal := medianCut(counts, n)
+	out := image.NewPaletted(b, pal)
+	index := make(map[color.NRGBA]uint8, len(counts))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			i, ok := index[c]
+			if !ok {
+				i = uint8(pal.Index(c))
+				index[c] = i
+			}
+			out.SetColorIndex(x, y, i)
+		}
+	}
+	return out
+}
+
+// addColours counts the colours of img within r.
+func addColours(counts map[color.NRGBA]int, img image.Image, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			counts[color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)]++
+		}
+	}
+}
+
+// swatch is a distinct colour and how many pixels have it.
+type swatch struct {
+	c     [4]uint8
+	count int
+}
+
+// medianCut splits the colours into n boxes, halving the one with the widest
+// spread each time, and averages each box into a palette entry.
+func medianCut(counts map[color.NRGBA]int, n int) color.Palette {
+	swatches := make([]swatch, 0, len(counts))
+	for c, count := range counts {
+		swatches = append(swatches, swatch{[4]uint8{c.R, c.G, c.B, c.A}, count})
+	}
+	// map order is random, sorting keeps the palette the same between runs
+	slices.SortFunc(swatches, func(a, b swatch) int {
+		return cmp.Or(cmp.Compare(a.c[0], b.c[0]), cmp.Compare(a.c[1], b.c[1]), cmp.Compare(a.c[2], b.c[2]), cmp.Compare(a.c[3], b.c[3]))
+	})
+
+	boxes := [][]swatch{swatches}
+	if len(swatches) <= n {
+		boxes = boxes[:0]
+		for i := range swatches {
+			boxes = append(boxes, swatches[i:i+1])
+		}
+	}
+	for len(boxes) < n {
+		widest, channel, spread := -1, 0, 0
+		for i, box := range boxes {
+			ch, s := widestChannel(box)
+			// weigh by pixels so big areas get the finer shades
+			if s*len(box) > spread {
+				widest, channel, spread = i, ch, s*len(box)
+			}
+		}
+		if widest < 0 {
+			break
+		}
+
+		box := boxes[widest]
+		slices.SortStableFunc(box, func(a, b swatch) int { return cmp.Compare(a.c[channel], b.c[channel]) })
+		total := 0
+		for _, s := range box {
+			total += s.count
+		}
+		split, seen := 1, box[0].count
+		for split < len(box)-1 && seen*2 < total {
+			seen += box[split].count
+			split++
+		}
+		boxes[widest] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	pal := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		var sum [4]int
+		total := 0
+		for _, s := range box {
+			for ch := range sum {
+				sum[ch] += int(s.c[ch]) * s.count
+			}
+			total += s.count
+		}
+		var c [4]uint8
+		for ch := range c {
+			c[ch] = uint8((sum[ch] + total/2) / total)
+		}
+		pal[i] = color.NRGBA{c[0], c[1], c[2], c[3]}
+	}
+	return pal
+}
+
+// widestChannel returns the channel the box's colours spread furthest
+// along, and how far. A single colour has no spread.
+func widestChannel(box []swatch) (int, int) {
+	channel, spread := 0, 0
+	for ch := range 4 {
+		lo, hi := 255, 0
+		for _, s := range box {
+			lo, hi = min(lo, int(s.c[ch])), max(hi, int(s.c[ch]))
+		}
+		if hi-lo > spread {
+			channel, spread = ch, hi-lo
+		}
+	}
+	return channel, spread
+}
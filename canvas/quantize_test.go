@@ -0,0 +1,56 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestQuantizeKeepsFewColours(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	colours := []color.NRGBA{{10, 20, 30, 255}, {200, 0, 0, 255}, {0, 0, 0, 0}}
+	for y := range 8 {
+		for x := range 8 {
+			img.SetNRGBA(x, y, colours[(x+y)%len(colours)])
+		}
+	}
+
+	q := Quantize(img, 256)
+	if len(q.Palette) != len(colours) {
+		t.Errorf("palette has %d colours, want %d", len(q.Palette), len(colours))
+	}
+	for y := range 8 {
+		for x := range 8 {
+			if got, want := color.NRGBAModel.Convert(q.At(x, y)), colours[(x+y)%len(colours)]; got != want {
+				t.Fatalf("pixel %d,%d is %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestQuantizeManyColours(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 256, 64))
+	for y := range 64 {
+		for x := range 256 {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y * 4), uint8(255 - x), 255})
+		}
+	}
+
+	q := Quantize(img, 64)
+	if len(q.Palette) != 64 {
+		t.Errorf("palette has %d colours, want 64", len(q.Palette))
+	}
+	worst := 0
+	for y := range 64 {
+		for x := range 256 {
+			a := img.NRGBAAt(x, y)
+			b := color.NRGBAModel.Convert(q.At(x, y)).(color.NRGBA)
+			for _, d := range []int{int(a.R) - int(b.R), int(a.G) - int(b.G), int(a.B) - int(b.B)} {
+				worst = max(worst, d, -d)
+			}
+		}
+	}
+	if worst > 40 {
+		t.Errorf("a channel is off by %d", worst)
+	}
+}
@@ -0,0 +1,286 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// SVG records drawing as an SVG document. Text stays text in the fonts it
+// was drawn with, which are embedded, and is measured with gg so anything
+// anchored to it lands where it does in a PNG.
+type SVG struct {
+	width, height int
+
+	color     color.NRGBA
+	lineWidth float64
+
+	measure  *gg.Context // holds the current face for measuring
+	font     string      // family of the current face
+	fontSize float64
+	fonts    map[string]string // font file to family
+	faces    []string          // @font-face rules in the order loaded
+
+	path       strings.Builder
+	hasCurrent bool
+	startX     float64
+	startY     float64
+
+	body strings.Builder
+}
+
+// NewSVG returns an empty width by height document.
+func NewSVG(width, height int) *SVG {
+	return &SVG{
+		width:     width,
+		height:    height,
+		color:     color.NRGBA{A: 255},
+		lineWidth: 1,
+		measure:   gg.NewContext(1, 1),
+		fonts:     map[string]string{},
+	}
+}
+
+var _ Canvas = (*SVG)(nil)
+
+// WriteTo writes the document.
+func (s *SVG) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" xml:space="preserve">`,
+		s.width, s.height, s.width, s.height)
+	if len(s.faces) > 0 {
+		b.WriteString("<style>")
+		for _, face := range s.faces {
+			b.WriteString(face)
+		}
+		b.WriteString("</style>")
+	}
+	b.WriteString(s.body.String())
+	b.WriteString("</svg>\n")
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Clear paints over everything drawn so far, like gg it replaces rather
+// than blends.
+func (s *SVG) Clear() {
+	s.body.Reset()
+	fmt.Fprintf(&s.body, `<rect width="%d" height="%d"%s/>`, s.width, s.height, s.paint("fill"))
+}
+
+func (s *SVG) SetColor(c color.Color) {
+	s.color = color.NRGBAModel.Convert(c).(color.NRGBA)
+}
+
+// SetHexColor takes the same #rgb, #rrggbb and #rrggbbaa forms as gg.
+func (s *SVG) SetHexColor(x string) {
+	x = strings.TrimPrefix(x, "#")
+	if len(x) == 3 {
+		x = string([]byte{x[0], x[0], x[1], x[1], x[2], x[2]})
+	}
+	if len(x) == 6 {
+		x += "ff"
+	}
+	v, err := strconv.ParseUint(x, 16, 32)
+	if len(x) != 8 || err != nil {
+		// gg reads junk as black
+		v = 0xff
+	}
+	s.color = color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}
+}
+
+func (s *SVG) SetRGB(r, g, b float64) {
+	s.color = color.NRGBA{uint8(r * 255), uint8(g * 255), uint8(b * 255), 255}
+}
+
+func (s *SVG) SetLineWidth(lineWidth float64) {
+	s.lineWidth = lineWidth
+}
+
+// paint is the attribute setting the current colour for fill or stroke.
+func (s *SVG) paint(attr string) string {
+	c := s.color
+	out := fmt.Sprintf(` %s="#%02x%02x%02x"`, attr, c.R, c.G, c.B)
+	if c.A != 255 {
+		out += fmt.Sprintf(` %s-opacity="%s"`, attr, num(float64(c.A)/255))
+	}
+	return out
+}
+
+// LoadFontFace embeds the font the first time it's used.
+func (s *SVG) LoadFontFace(path string, points float64) error {
+	if err := s.measure.LoadFontFace(path, points); err != nil {
+		return err
+	}
+	family, ok := s.fonts[path]
+	if !ok {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		family = fmt.Sprintf("font%d", len(s.fonts))
+		s.fonts[path] = family
+		s.faces = append(s.faces, fmt.Sprintf(`@font-face{font-family:%s;src:url(data:font/ttf;base64,%s) format("truetype")}`,
+			family, base64.StdEncoding.EncodeToString(raw)))
+	}
+	s.font, s.fontSize = family, points
+	return nil
+}
+
+func (s *SVG) FontHeight() float64 {
+	return s.measure.FontHeight()
+}
+
+func (s *SVG) MeasureString(text string) (w, h float64) {
+	return s.measure.MeasureString(text)
+}
+
+// DrawString puts text's baseline at y, as gg does.
+func (s *SVG) DrawString(text string, x, y float64) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	fmt.Fprintf(&s.body, `<text x="%s" y="%s" font-family="%s" font-size="%s"%s>`,
+		num(x), num(y), s.font, num(s.fontSize), s.paint("fill"))
+	xml.EscapeText(&s.body, []byte(text))
+	s.body.WriteString("</text>")
+}
+
+func (s *SVG) DrawStringAnchored(text string, x, y, ax, ay float64) {
+	w, h := s.MeasureString(text)
+	s.DrawString(text, x-ax*w, y+ay*h)
+}
+
+func (s *SVG) MoveTo(x, y float64) {
+	fmt.Fprintf(&s.path, "M%s %s", num(x), num(y))
+	s.hasCurrent = true
+	s.startX, s.startY = x, y
+}
+
+func (s *SVG) LineTo(x, y float64) {
+	if !s.hasCurrent {
+		s.MoveTo(x, y)
+		return
+	}
+	fmt.Fprintf(&s.path, "L%s %s", num(x), num(y))
+}
+
+func (s *SVG) ClosePath() {
+	if s.hasCurrent {
+		s.path.WriteString("Z")
+	}
+}
+
+// newSubPath leaves the next point to start a new subpath.
+func (s *SVG) newSubPath() {
+	s.hasCurrent = false
+}
+
+func (s *SVG) DrawLine(x1, y1, x2, y2 float64) {
+	s.MoveTo(x1, y1)
+	s.LineTo(x2, y2)
+}
+
+func (s *SVG) DrawRectangle(x, y, w, h float64) {
+	s.newSubPath()
+	s.MoveTo(x, y)
+	s.LineTo(x+w, y)
+	s.LineTo(x+w, y+h)
+	s.LineTo(x, y+h)
+	s.ClosePath()
+}
+
+// DrawRoundedRectangle follows gg's outline so both fill the same area.
+func (s *SVG) DrawRoundedRectangle(x, y, w, h, r float64) {
+	x0, x1, x2, x3 := x, x+r, x+w-r, x+w
+	y0, y1, y2, y3 := y, y+r, y+h-r, y+h
+	s.newSubPath()
+	s.MoveTo(x1, y0)
+	s.LineTo(x2, y0)
+	s.DrawArc(x2, y1, r, gg.Radians(270), gg.Radians(360))
+	s.LineTo(x3, y2)
+	s.DrawArc(x2, y2, r, gg.Radians(0), gg.Radians(90))
+	s.LineTo(x1, y3)
+	s.DrawArc(x1, y2, r, gg.Radians(90), gg.Radians(180))
+	s.LineTo(x0, y1)
+	s.DrawArc(x1, y1, r, gg.Radians(180), gg.Radians(270))
+	s.ClosePath()
+}
+
+func (s *SVG) DrawCircle(x, y, r float64) {
+	s.newSubPath()
+	s.DrawArc(x, y, r, 0, 2*math.Pi)
+	s.ClosePath()
+}
+
+// DrawArc joins the arc to the current point like gg. It's written in
+// quarter turns at most, since SVG can't draw an arc that ends where it starts.
+func (s *SVG) DrawArc(x, y, r, angle1, angle2 float64) {
+	s.LineTo(x+r*math.Cos(angle1), y+r*math.Sin(angle1))
+	sweep := 0
+	if angle2 > angle1 {
+		sweep = 1
+	}
+	n := max(1, int(math.Ceil(math.Abs(angle2-angle1)/(math.Pi/2))))
+	for i := 1; i <= n; i++ {
+		a := angle1 + (angle2-angle1)*float64(i)/float64(n)
+		fmt.Fprintf(&s.path, "A%s %s 0 0 %d %s %s", num(r), num(r), sweep, num(x+r*math.Cos(a)), num(y+r*math.Sin(a)))
+	}
+}
+
+// Fill fills the path with the nonzero rule, gg's default, and clears it.
+func (s *SVG) Fill() {
+	if s.path.Len() > 0 {
+		fmt.Fprintf(&s.body, `<path d="%s"%s/>`, s.path.String(), s.paint("fill"))
+	}
+	s.clearPath()
+}
+
+// Stroke outlines the path with gg's round caps and joins, and clears it.
+func (s *SVG) Stroke() {
+	if s.path.Len() > 0 {
+		fmt.Fprintf(&s.body, `<path d="%s" fill="none"%s stroke-width="%s" stroke-linecap="round" stroke-linejoin="round"/>`,
+			s.path.String(), s.paint("stroke"), num(s.lineWidth))
+	}
+	s.clearPath()
+}
+
+func (s *SVG) clearPath() {
+	s.path.Reset()
+	s.hasCurrent = false
+}
+
+func (s *SVG) DrawImage(im image.Image, x, y int) {
+	s.DrawImageAnchored(im, x, y, 0, 0)
+}
+
+// DrawImageAnchored embeds the image as a PNG, placed like gg places it.
+func (s *SVG) DrawImageAnchored(im image.Image, x, y int, ax, ay float64) {
+	size := im.Bounds().Size()
+	x -= int(ax * float64(size.X))
+	y -= int(ay * float64(size.Y))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, im); err != nil {
+		// only an empty image fails, and there's nothing to draw
+		return
+	}
+	fmt.Fprintf(&s.body, `<image x="%d" y="%d" width="%d" height="%d" href="data:image/png;base64,%s"/>`,
+		x, y, size.X, size.Y, base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+// num writes a coordinate to a hundredth of a pixel, plenty for any screen.
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,101 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/fogleman/gg"
+)
+
+func TestSVG(t *testing.T) {
+	doc := NewSVG(200, 100)
+	doc.SetHexColor("#123")
+	doc.Clear()
+	if err := doc.LoadFontFace("../statscard/fonts/minecraft.ttf", 16); err != nil {
+		t.Fatal(err)
+	}
+	doc.SetColor(color.NRGBA{255, 0, 0, 128})
+	doc.DrawStringAnchored("a < b & c", 100, 50, 0.5, 0.5)
+	if err := doc.LoadFontFace("../statscard/fonts/minecraft.ttf", 24); err != nil {
+		t.Fatal(err)
+	}
+	doc.DrawString("again", 0, 90)
+	doc.DrawCircle(50, 50, 10)
+	doc.Fill()
+	doc.SetLineWidth(2)
+	doc.DrawLine(0, 0, 200, 100)
+	doc.Stroke()
+	doc.DrawImageAnchored(image.NewRGBA(image.Rect(0, 0, 4, 2)), 10, 10, 0.5, 0.5)
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if err := xml.NewDecoder(&buf).Decode(new(struct{})); err != nil {
+		t.Fatalf("not well formed: %v\n%s", err, out)
+	}
+
+	for _, want := range []string{
+		`<rect width="200" height="100" fill="#112233"/>`,
+		`>a &lt; b &amp; c</text>`,
+		`fill="#ff0000" fill-opacity="0.5"`,
+		`stroke="#ff0000" stroke-opacity="0.5" stroke-width="2"`,
+		`<image x="8" y="9" width="4" height="2"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "@font-face"); n != 1 {
+		t.Errorf("embedded the font %d times", n)
+	}
+}
+
+// Text drawn to an SVG is placed where gg would put it.
+func TestSVGAnchorsTextLikeGG(t *testing.T) {
+	const font = "../statscard/fonts/comfortaa.ttf"
+	dc := gg.NewContext(1, 1)
+	doc := NewSVG(1, 1)
+	for _, c := range []Canvas{dc, doc} {
+		if err := c.LoadFontFace(font, 20); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, h := dc.MeasureString("player stats")
+	if sw, sh := doc.MeasureString("player stats"); sw != w || sh != h || doc.FontHeight() != dc.FontHeight() {
+		t.Errorf("measured %vx%v, gg measures %vx%v", sw, sh, w, h)
+	}
+
+	doc.DrawStringAnchored("player stats", 100, 50, 1, 0.5)
+	want := `<text x="` + num(100-w) + `" y="` + num(50+h/2) + `"`
+	if !strings.HasPrefix(doc.body.String(), want) {
+		t.Errorf("drew %s, want it to start %s", doc.body.String(), want)
+	}
+}
+
+// A whole turn has to be split, SVG draws nothing for an arc back to its start.
+func TestSVGFullCircle(t *testing.T) {
+	doc := NewSVG(100, 100)
+	doc.DrawArc(50, 50, 10, 0, 2*math.Pi)
+	path := doc.path.String()
+	if n := strings.Count(path, "A"); n != 4 {
+		t.Errorf("%s has %d arcs, want 4 quarter turns", path, n)
+	}
+	if !strings.HasPrefix(path, "M60 50") || !strings.HasSuffix(path, " 60 50") {
+		t.Errorf("%s doesn't go round from 60,50 and back", path)
+	}
+
+	// counterclockwise, like the inner edge of a donut slice
+	doc = NewSVG(100, 100)
+	doc.MoveTo(50, 50)
+	doc.DrawArc(50, 50, 10, math.Pi/2, 0)
+	if path := doc.path.String(); path != "M50 50L50 60A10 10 0 0 0 60 50" {
+		t.Errorf("got %s", path)
+	}
+}
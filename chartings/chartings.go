@@ -2,13 +2,14 @@ package chartings
 
 import (
 	"bytes"
-	"fmt"
-	"log"
+	"cmp"
+	"context"
+	"image/color"
+	"io"
 	"math"
-	"time"
+	"strconv"
 
-	"github.com/bwmarrin/discordgo"
-	"github.com/fogleman/gg"
+	"wynn_bot/canvas"
 )
 
 // "fmt"
@@ -18,7 +19,16 @@ import (
 
 // "github.com/fogleman/gg"
 
+// Kind is how a chart draws its points.
+type Kind int
+
+const (
+	Scatter Kind = iota // a dot per point
+	Bars                // a bar per Y, labelled with XLegends, X is unused
+)
+
 type ChartData struct {
+	Kind     Kind
 	X        []float64 // actual X position
 	Y        []float64 // actual Y position
 	XLegends []string  // i'm assuming this is what is displayed
@@ -29,20 +39,49 @@ type ChartData struct {
 	Desc     string
 	Width    int
 	Height   int
+
+	// Stacked are more series of bars drawn on top of Y's
+	Stacked [][]float64
+	// Colors are hex colours for Y then each of Stacked, grey past the end
+	Colors []string
+	// Background and Foreground default to white and black
+	Background color.Color
+	Foreground color.Color
+}
+
+// Returns image buffer and error, or ctx's error if it ends first
+func Render(ctx context.Context, d *ChartData) (*bytes.Buffer, error) {
+	buffer := new(bytes.Buffer)
+	if err := Encode(ctx, buffer, canvas.FormatPNG, d); err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// Encode draws the chart and writes it to w as f, or stops with ctx's error
+// if it ends first.
+func Encode(ctx context.Context, w io.Writer, f canvas.Format, d *ChartData) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return canvas.Encode(w, f, d.Width, d.Height, func(chart canvas.Canvas) error {
+		Draw(chart, d)
+		// checked before encoding, which is most of the work for a bitmap
+		return ctx.Err()
+	})
 }
 
-// Returns image buffer and error
-func Render(d *ChartData) (*bytes.Buffer, error) {
-	fmt.Println("test")
-	chart := gg.NewContext(d.Width, d.Height)
-	chart.SetRGB(1, 1, 1) // Background color
+// Draw draws the chart over the whole of chart, which should be d.Width by
+// d.Height.
+func Draw(chart canvas.Canvas, d *ChartData) {
+	chart.SetColor(cmp.Or[color.Color](d.Background, color.White))
 	chart.Clear()
 
 	width := d.Width
 	height := d.Height
 
 	// Draw title
-	chart.SetRGB(0, 0, 0)
+	chart.SetColor(cmp.Or[color.Color](d.Foreground, color.Black))
 	chart.LoadFontFace("statscard/fonts/comfortaa.ttf", 24)
 	chart.DrawStringAnchored(d.Title, float64(width)/2, 30, 0.5, 0.5)
 
@@ -51,16 +90,6 @@ func Render(d *ChartData) (*bytes.Buffer, error) {
 	chartWidth := float64(width) - 2*margin
 	chartHeight := float64(height) - 2*margin - 40 // Adjust for title
 
-	// Find min/max for axes
-	xMin, xMax := math.Inf(1), math.Inf(-1)
-	yMin, yMax := math.Inf(1), math.Inf(-1)
-	for i := range d.X {
-		xMin = math.Min(xMin, d.X[i])
-		xMax = math.Max(xMax, d.X[i])
-		yMin = math.Min(yMin, d.Y[i])
-		yMax = math.Max(yMax, d.Y[i])
-	}
-
 	// Draw axes
 	chart.SetLineWidth(2)
 	chart.DrawLine(margin, float64(height)-margin, margin, margin)
@@ -72,6 +101,21 @@ func Render(d *ChartData) (*bytes.Buffer, error) {
 	chart.DrawStringAnchored(d.XLabel, float64(width)/2, float64(height)-20, 0.5, 0.5)
 	chart.DrawStringAnchored(d.YLabel, 20, float64(height)/2, 0.5, 0.5)
 
+	if d.Kind == Bars {
+		drawBars(chart, d, margin, chartWidth, chartHeight)
+		return
+	}
+
+	// Find min/max for axes
+	xMin, xMax := math.Inf(1), math.Inf(-1)
+	yMin, yMax := math.Inf(1), math.Inf(-1)
+	for i := range d.X {
+		xMin = math.Min(xMin, d.X[i])
+		xMax = math.Max(xMax, d.X[i])
+		yMin = math.Min(yMin, d.Y[i])
+		yMax = math.Max(yMax, d.Y[i])
+	}
+
 	// Plot points
 	for i := range d.X {
 		xNorm := (d.X[i] - xMin) / (xMax - xMin)
@@ -83,76 +127,54 @@ func Render(d *ChartData) (*bytes.Buffer, error) {
 		chart.DrawCircle(x, y, 4)
 		chart.Fill()
 	}
-
-	// Save to buffer
-	buffer := new(bytes.Buffer)
-	if err := chart.EncodePNG(buffer); err != nil {
-		return nil, err
-	}
-
-	return buffer, nil
 }
 
-func ChartTest(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: "Chart generating, please wait...",
-		},
-	})
-	if err != nil {
-		log.Printf("Could not respond to interaction: %s", err)
+// drawBars draws a bar per Y from zero, with Stacked on top and the legends
+// under them, scaled so the tallest stack fits.
+func drawBars(chart canvas.Canvas, d *ChartData, margin, chartWidth, chartHeight float64) {
+	series := append([][]float64{d.Y}, d.Stacked...)
+	n := len(d.Y)
+	if n == 0 {
 		return
 	}
-
-	// Example chart data
-	data := &ChartData{
-		X:        []float64{1, 2, 3, 4, 5},
-		Y:        []float64{10, 20, 15, 25, 30},
-		XLegends: []string{"A", "B", "C", "D", "E"},
-		YLegends: []string{"Low", "Medium", "High", "Very High"},
-		XLabel:   "Categories",
-		YLabel:   "Values",
-		Title:    "Test Chart",
-		Desc:     "This is a test chart",
-	}
-
-	buffer, err := Render(data)
-	if err != nil {
-		log.Printf("Failed to render chart: %s", err)
-		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPointer("Failed to generate chart."),
-		})
-		return
+	totals := make([]float64, n)
+	yMax := 0.0
+	for i := range totals {
+		for _, s := range series {
+			if i < len(s) {
+				totals[i] += s[i]
+			}
+		}
+		yMax = math.Max(yMax, totals[i])
 	}
 
-	// Retry logic for editing the response
-	maxRetries := 5
-	for attempts := 0; attempts < maxRetries; attempts++ {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPointer("Chart generated!"),
-			Files: []*discordgo.File{
-				{
-					Name:   "chart.png",
-					Reader: buffer,
-				},
-			},
-		})
-		if err == nil {
-			break // Success
+	bottom := float64(d.Height) - margin
+	slot := chartWidth / float64(n)
+	barWidth := slot * 0.7
+	for i := range n {
+		x := margin + slot*float64(i) + (slot-barWidth)/2
+		y := bottom
+		for j, s := range series {
+			if i >= len(s) || s[i] <= 0 || yMax == 0 {
+				continue
+			}
+			h := s[i] / yMax * chartHeight
+			colour := "#888888"
+			if j < len(d.Colors) {
+				colour = d.Colors[j]
+			}
+			chart.SetHexColor(colour)
+			chart.DrawRectangle(x, y-h, barWidth, h)
+			chart.Fill()
+			y -= h
 		}
-		log.Printf("Retry %d: Failed to edit interaction response with image: %s", attempts+1, err)
-		time.Sleep(time.Second) // Wait before retrying
-	}
 
-	if err != nil {
-		log.Printf("Failed to edit interaction response after retries: %s", err)
-		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPointer("Failed to edit interaction response after multiple attempts."),
-		})
+		chart.SetColor(cmp.Or[color.Color](d.Foreground, color.Black))
+		if i < len(d.XLegends) {
+			chart.DrawStringAnchored(d.XLegends[i], x+barWidth/2, bottom+14, 0.5, 0.5)
+		}
+		if totals[i] > 0 {
+			chart.DrawStringAnchored(strconv.FormatFloat(totals[i], 'f', -1, 64), x+barWidth/2, y-10, 0.5, 0.5)
+		}
 	}
 }
-
-func stringPointer(s string) *string {
-	return &s
-}
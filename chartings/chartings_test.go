@@ -0,0 +1,77 @@
+package chartings
+
+import (
+	"context"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"wynn_bot/internal/golden"
+)
+
+func TestRender(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fonts are loaded relative to the repo root
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	buffer, err := Render(context.Background(), &ChartData{
+		X:      []float64{1, 2, 3, 4, 5},
+		Y:      []float64{10, 20, 15, 25, 30},
+		XLabel: "Categories",
+		YLabel: "Values",
+		Title:  "Test Chart",
+		Width:  600,
+		Height: 400,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := golden.Default
+	opts.Dir = filepath.Join(wd, "testdata")
+	golden.Assert(t, "scatter", img, opts)
+}
+
+func TestRenderBars(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	buffer, err := Render(context.Background(), &ChartData{
+		Kind:     Bars,
+		Y:        []float64{20, 8, 0, 3},
+		Stacked:  [][]float64{{5, 0, 0, 9}},
+		XLegends: []string{"DS", "IP", "LS", "UC"},
+		Colors:   []string{"#5fa8d3", "#b34036"},
+		Title:    "Stacked",
+		Width:    400,
+		Height:   300,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := golden.Default
+	opts.Dir = filepath.Join(wd, "testdata")
+	golden.Assert(t, "bars", img, opts)
+}
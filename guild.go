@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"log"
+	"time"
+
+	"wynn_bot/banner"
+	"wynn_bot/canvas"
+	"wynn_bot/models"
+	"wynn_bot/statscard"
+	"wynn_bot/wynnapi"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// the /banner output is drawn at twice the pattern size
+const bannerScale = 2
+
+// guildImage is a picture of a guild: its banner, or its card.
+type guildImage struct {
+	name  string // names the attachment and the render time metric
+	title string // what the replies call it
+	write func(ctx context.Context, w io.Writer, guild models.GuildData, opts statscard.Options) error
+}
+
+var (
+	bannerImage = guildImage{"banner", "banner", func(ctx context.Context, w io.Writer, guild models.GuildData, _ statscard.Options) error {
+		img, err := banner.Default.Poster(guild, bannerScale, banner.Colors)
+		if err != nil {
+			return err
+		}
+		return png.Encode(w, img)
+	}}
+	guildCardImage = guildImage{"guild", "guild card", func(ctx context.Context, w io.Writer, guild models.GuildData, opts statscard.Options) error {
+		return statscard.WriteGuildCard(ctx, w, canvas.FormatPNG, guild, opts)
+	}}
+)
+
+func getGuildImage(s responder, i *discordgo.InteractionCreate, opts optionMap, g guildImage) {
+	waiting := "Generating " + g.title + ", please wait..."
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: waiting,
+		},
+	})
+	if err != nil {
+		log.Printf("Could not respond to interaction: %s", err)
+		countCommand(i, outcomeFailed)
+		return
+	}
+
+	enqueueRender(s, i, waiting, func(ctx context.Context) {
+		drawGuildImage(ctx, s, i, opts, g)
+	})
+}
+
+// drawGuildImage fetches the guild and sends g of it, run from the render queue.
+func drawGuildImage(ctx context.Context, s responder, i *discordgo.InteractionCreate, opts optionMap, g guildImage) {
+	query := opts["guild"].StringValue()
+	guild, err := findGuild(ctx, query)
+	if errors.Is(err, wynnapi.ErrNotFound) {
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer(fmt.Sprintf("Guild %s was not found.", query)),
+		})
+		countCommand(i, outcomeNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to fetch guild: %s", err)
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer("Failed to access the guild data URL."),
+		})
+		countCommand(i, outcomeAPIError)
+		return
+	}
+
+	start := time.Now()
+	var buffer bytes.Buffer
+	err = g.write(ctx, &buffer, guild, cardOptions(i, opts))
+	if ctx.Err() != nil {
+		dropRender(ctx, s, i, fmt.Sprintf("the %s for %s", g.title, guild.Name))
+		return
+	} else if err != nil {
+		log.Printf("Failed to draw %s: %s", g.title, err)
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer("Failed to generate " + g.title + "."),
+		})
+		countCommand(i, outcomeFailed)
+		return
+	}
+	renderDuration.Observe(time.Since(start).Seconds(), g.name)
+
+	err = editWithRetries(s, i, func() *discordgo.WebhookEdit {
+		return &discordgo.WebhookEdit{
+			Content: stringPointer(""),
+			Files: []*discordgo.File{
+				{
+					Name:   g.name + ".png",
+					Reader: bytes.NewReader(buffer.Bytes()),
+				},
+			},
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to edit interaction response after retries: %s", err)
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer("Failed to edit interaction response after multiple attempts."),
+		})
+		countCommand(i, outcomeFailed)
+		return
+	}
+	countCommand(i, outcomeOK)
+}
+
+// editWithRetries builds a fresh edit for every attempt, since a file reader
+// is used up by a failed upload.
+func editWithRetries(s responder, i *discordgo.InteractionCreate, edit func() *discordgo.WebhookEdit) error {
+	var err error
+	maxRetries := 5
+	for attempts := 0; attempts < maxRetries; attempts++ {
+		_, err = s.InteractionResponseEdit(i.Interaction, edit())
+		if err == nil {
+			return nil
+		}
+		log.Printf("Retry %d: Failed to edit interaction response with image: %s", attempts+1, err)
+		time.Sleep(editRetryDelay) // Wait before retrying
+	}
+	return err
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"wynn_bot/avatar"
+	"wynn_bot/models"
+	"wynn_bot/prefs"
+	"wynn_bot/queue"
+	"wynn_bot/statscard"
+	"wynn_bot/wynnapi"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// stubHandlers points the handlers at fixtures instead of the api and a
+// fresh preferences file, putting everything back after the test.
+func stubHandlers(t *testing.T) {
+	t.Helper()
+	oldPlayer, oldGuild, oldFind, oldRender, oldAvatars, oldDelay, oldPrefs, oldRenders :=
+		getPlayer, getGuild, findGuild, renderStatsCard, avatars, editRetryDelay, userPrefs, renders
+	t.Cleanup(func() {
+		getPlayer, getGuild, findGuild, renderStatsCard, avatars, editRetryDelay, userPrefs, renders =
+			oldPlayer, oldGuild, oldFind, oldRender, oldAvatars, oldDelay, oldPrefs, oldRenders
+	})
+
+	player := readFixture[models.PlayerData](t, "player.json")
+	guild := readFixture[models.GuildData](t, "guild.json")
+	getPlayer = func(_ context.Context, name string) (models.PlayerData, error) {
+		if !strings.EqualFold(name, player.Username) {
+			return models.PlayerData{}, wynnapi.ErrNotFound
+		}
+		return player, nil
+	}
+	getGuild = func(context.Context, string) (models.GuildData, error) { return guild, nil }
+	findGuild = func(_ context.Context, query string) (models.GuildData, error) {
+		if query != guild.Name && query != guild.Prefix {
+			return models.GuildData{}, wynnapi.ErrNotFound
+		}
+		return guild, nil
+	}
+	avatars = avatar.ProviderFunc(func(context.Context, avatar.Player) (image.Image, error) {
+		return avatar.Silhouette(), nil
+	})
+	editRetryDelay = 0
+
+	store, err := prefs.Open(filepath.Join(t.TempDir(), "preferences.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userPrefs = store
+	renders = queue.New(1, 1)
+}
+
+// readFixture reads one of the stats card's test players or guilds.
+func readFixture[T any](t *testing.T, name string) T {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("statscard", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatalf("failed to decode %s: %v", name, err)
+	}
+	return v
+}
+
+// discordEpoch is where snowflake timestamps count from, in milliseconds.
+const discordEpoch = 1420070400000
+
+// command builds a slash command sent just now by user-1.
+func command(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	id := (time.Now().UnixMilli() - discordEpoch) << 22
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:     strconv.FormatInt(id, 10),
+		Type:   discordgo.InteractionApplicationCommand,
+		Member: &discordgo.Member{User: &discordgo.User{ID: "user-1"}},
+		Data:   discordgo.ApplicationCommandInteractionData{Name: name, Options: options},
+	}}
+}
+
+func stringOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  discordgo.ApplicationCommandOptionString,
+		Value: value,
+	}
+}
+
+// run handles i and waits for any render it queued, for as long as the
+// test binary may run, since how long a render takes depends on the machine.
+func run(t *testing.T, f responder, i *discordgo.InteractionCreate) {
+	t.Helper()
+	handleCommand(f, i)
+	ctx, cancel := context.WithCancel(context.Background())
+	if deadline, ok := t.Deadline(); ok {
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+	}
+	defer cancel()
+	if err := renders.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func decodePNG(t *testing.T, raw []byte) image.Image {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("the attachment isn't a png: %v", err)
+	}
+	return img
+}
+
+func TestStatsCommand(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+
+	run(t, f, command("stats", stringOption("username", "starfaiien")))
+
+	if len(f.responses) != 1 || !strings.HasPrefix(f.responses[0].Data.Content, "Generating stats card") {
+		t.Fatalf("responded with %+v, want the waiting message", f.responses)
+	}
+	last := f.last()
+	if !strings.HasPrefix(last.content, "**starfaiien**") {
+		t.Errorf("sent %q, want the player summary", last.content)
+	}
+	card := decodePNG(t, last.files["statcard.png"])
+	if size := card.Bounds().Size(); size != image.Pt(562, 952) {
+		t.Errorf("card is %v", size)
+	}
+}
+
+func TestStatsCompact(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+
+	run(t, f, command("stats", stringOption("username", "starfaiien"), stringOption("format", formatCompact)))
+
+	card := decodePNG(t, f.last().files["statcard.png"])
+	if _, ok := card.(*image.Paletted); !ok {
+		t.Errorf("sent a %T, want a paletted image", card)
+	}
+}
+
+func TestStatsAnimated(t *testing.T) {
+	stubHandlers(t)
+	// a few frames are enough to tell it's animated, all 30 are slow under -race
+	oldAnimation := cardAnimation
+	t.Cleanup(func() { cardAnimation = oldAnimation })
+	cardAnimation.Frames = 4
+	f := &fakeResponder{}
+
+	run(t, f, command("stats", stringOption("username", "starfaiien"), stringOption("format", formatAnimated)))
+
+	anim, err := gif.DecodeAll(bytes.NewReader(f.last().files["statcard.gif"]))
+	if err != nil {
+		t.Fatalf("the attachment isn't a gif: %v", err)
+	}
+	if len(anim.Image) < 2 {
+		t.Errorf("sent %d frames", len(anim.Image))
+	}
+}
+
+func TestLevelHistory(t *testing.T) {
+	h := &levelHistory{levels: map[string]map[string]int{}}
+	player := readFixture[models.PlayerData](t, "player.json")
+	if got := h.update(player); got != nil {
+		t.Errorf("a player seen for the first time levelled up %v", got)
+	}
+	if got := h.update(player); got != nil {
+		t.Errorf("nothing changed but levelled up %v", got)
+	}
+
+	before := statscard.ClassLevels(player)
+	char := player.Characters["c3"]
+	char.Level++
+	player.Characters["c3"] = char
+	got := h.update(player)
+	if len(got) != 1 || got[char.Type] != before[char.Type] {
+		t.Errorf("levelled up %v, want only %s from %d", got, char.Type, before[char.Type])
+	}
+}
+
+func TestStatsCommandErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		err     error
+		options []*discordgo.ApplicationCommandInteractionDataOption
+		want    string
+	}{
+		{"not found", nil, []*discordgo.ApplicationCommandInteractionDataOption{stringOption("username", "nobody")}, "Player nobody was not found."},
+		{"no username", nil, nil, "No username given and no linked account, use /link to link yours."},
+		{"upstream", fmt.Errorf("%w: status 503 Service Unavailable", wynnapi.ErrRequest), nil, "Failed to access the player data URL."},
+		{"decode", fmt.Errorf("%w: unexpected end of JSON input", wynnapi.ErrDecode), nil, "Failed to decode player data JSON."},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stubHandlers(t)
+			if tt.err != nil {
+				getPlayer = func(context.Context, string) (models.PlayerData, error) {
+					return models.PlayerData{}, tt.err
+				}
+				tt.options = append(tt.options, stringOption("username", "starfaiien"))
+			}
+			f := &fakeResponder{}
+
+			run(t, f, command("stats", tt.options...))
+
+			if last := f.last(); last.content != tt.want || len(last.files) > 0 {
+				t.Errorf("sent %q with %d files, want %q", last.content, len(last.files), tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsRespondFails(t *testing.T) {
+	stubHandlers(t)
+	before := commandsHandled.Value("stats", outcomeFailed)
+	f := &fakeResponder{failRespond: true}
+
+	run(t, f, command("stats", stringOption("username", "starfaiien")))
+
+	if len(f.edits) > 0 {
+		t.Errorf("edited a reply that was never sent: %+v", f.edits)
+	}
+	if got := commandsHandled.Value("stats", outcomeFailed) - before; got != 1 {
+		t.Errorf("counted %v failed, want 1", got)
+	}
+}
+
+func TestExpiredRenderIsReported(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		shutdown bool
+		want     string
+		outcome  string
+	}{
+		{"token expired", false, "Your card waited too long in the queue, try again.", outcomeExpired},
+		{"shutting down", true, restartingMessage, outcomeRestarting},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stubHandlers(t)
+			i := command("stats", stringOption("username", "starfaiien"))
+			if tt.shutdown {
+				oldRoot := rootCtx
+				t.Cleanup(func() { rootCtx = oldRoot })
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				rootCtx = ctx
+			} else {
+				// sent longer ago than the token lasts
+				sent := time.Now().Add(-interactionLifetime - time.Minute)
+				i.ID = strconv.FormatInt((sent.UnixMilli()-discordEpoch)<<22, 10)
+			}
+			getPlayer = func(context.Context, string) (models.PlayerData, error) {
+				t.Error("an expired render fetched the player")
+				return models.PlayerData{}, nil
+			}
+			before := commandsHandled.Value("stats", tt.outcome)
+			f := &fakeResponder{}
+
+			run(t, f, i)
+
+			if last := f.last().content; last != tt.want {
+				t.Errorf("sent %q, want %q", last, tt.want)
+			}
+			if got := commandsHandled.Value("stats", tt.outcome) - before; got != 1 {
+				t.Errorf("counted %v %s, want 1", got, tt.outcome)
+			}
+		})
+	}
+}
+
+func TestStatsEmbed(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+
+	run(t, f, command("stats", stringOption("username", "starfaiien"), stringOption("format", formatEmbed)))
+
+	last := f.last()
+	if len(last.embeds) != 1 || !strings.HasPrefix(last.embeds[0].Title, "starfaiien") {
+		t.Fatalf("sent %+v, want the stats embed", last)
+	}
+	if last.content != "" {
+		t.Errorf("sent %q above an embed that was asked for", last.content)
+	}
+	if _, ok := last.files["statcard.png"]; ok {
+		t.Error("drew the card anyway")
+	}
+	decodePNG(t, last.files[statscard.BadgeName])
+}
+
+// A hung render gives up long before the token would, freeing its worker.
+func TestRenderTimeout(t *testing.T) {
+	stubHandlers(t)
+	old := renderTimeout
+	t.Cleanup(func() { renderTimeout = old })
+	renderTimeout = 20 * time.Millisecond
+	renderStatsCard = func(ctx context.Context, _ models.PlayerData, _ *models.GuildData, _ statscard.Options) (image.Image, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	before := commandsHandled.Value("stats", outcomeExpired)
+	f := &fakeResponder{}
+
+	run(t, f, command("stats", stringOption("username", "starfaiien")))
+
+	if want := "Drawing your card took too long, try again."; f.last().content != want {
+		t.Errorf("sent %q, want %q", f.last().content, want)
+	}
+	if got := commandsHandled.Value("stats", outcomeExpired) - before; got != 1 {
+		t.Errorf("counted %v expired, want 1", got)
+	}
+}
+
+func TestStatsFallsBackToEmbed(t *testing.T) {
+	stubHandlers(t)
+	renderStatsCard = func(context.Context, models.PlayerData, *models.GuildData, statscard.Options) (image.Image, error) {
+		return nil, errors.New("failed to load background")
+	}
+	f := &fakeResponder{}
+
+	run(t, f, command("stats", stringOption("username", "starfaiien")))
+
+	last := f.last()
+	if last.content != cardFailedNote || len(last.embeds) != 1 {
+		t.Errorf("sent %q with %d embeds, want the fallback embed", last.content, len(last.embeds))
+	}
+}
+
+func TestBannerCommand(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+
+	run(t, f, command("banner", stringOption("guild", "SEQ")))
+	if _, ok := f.last().files["banner.png"]; !ok {
+		t.Errorf("sent %q without a banner", f.last().content)
+	}
+
+	run(t, f, command("banner", stringOption("guild", "Nobody")))
+	if want := "Guild Nobody was not found."; f.last().content != want {
+		t.Errorf("sent %q, want %q", f.last().content, want)
+	}
+}
+
+func TestGuildCommand(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+
+	run(t, f, command("guild", stringOption("guild", "SEQ")))
+	if _, ok := f.last().files["guild.png"]; !ok {
+		t.Errorf("sent %q without a guild card", f.last().content)
+	}
+	if !strings.HasPrefix(f.responses[0].Data.Content, "Generating guild card") {
+		t.Errorf("replied %q", f.responses[0].Data.Content)
+	}
+}
+
+func TestRaidsCommand(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+
+	run(t, f, command("raids", stringOption("username", "starfaiien")))
+	card := decodePNG(t, f.last().files["raids.png"])
+	if size := card.Bounds().Size(); size.X != 562 {
+		t.Errorf("card is %v, want as wide as the stats card", size)
+	}
+
+	run(t, f, command("raids", stringOption("username", "nobody")))
+	if want := "Player nobody was not found."; f.last().content != want {
+		t.Errorf("sent %q, want %q", f.last().content, want)
+	}
+}
+
+func TestDungeonsCommand(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+
+	run(t, f, command("dungeons"))
+	if want := "No username given and no linked account, use /link to link yours."; f.last().content != want {
+		t.Errorf("sent %q, want %q", f.last().content, want)
+	}
+
+	run(t, f, command("dungeons", stringOption("username", "starfaiien")))
+	card := decodePNG(t, f.last().files["dungeons.png"])
+	if size := card.Bounds().Size(); size.X != 562 {
+		t.Errorf("card is %v, want as wide as the stats card", size)
+	}
+}
+
+func TestProfessionsCommand(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+
+	run(t, f, command("professions", stringOption("username", "starfaiien"), stringOption("character", "mage")))
+	if _, ok := f.last().files["professions.png"]; !ok {
+		t.Errorf("sent %q without a card", f.last().content)
+	}
+
+	run(t, f, command("professions", stringOption("username", "starfaiien"), stringOption("character", "assassin")))
+	if want := "starfaiien has no character matching assassin."; f.last().content != want {
+		t.Errorf("sent %q, want %q", f.last().content, want)
+	}
+}
+
+func TestEditRetries(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{failUploads: 2}
+
+	run(t, f, command("banner", stringOption("guild", "Sequoia")))
+
+	var attempts [][]byte
+	for _, edit := range f.edits {
+		if raw, ok := edit.files["banner.png"]; ok {
+			attempts = append(attempts, raw)
+		}
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("uploaded %d times, want 2 failures and a success", len(attempts))
+	}
+	// every attempt sends the whole file, not what a failed one left unread
+	for i, raw := range attempts {
+		decodePNG(t, raw)
+		if !bytes.Equal(raw, attempts[0]) {
+			t.Errorf("attempt %d sent a different file", i+1)
+		}
+	}
+	if _, ok := f.last().files["banner.png"]; !ok {
+		t.Error("the banner wasn't sent in the end")
+	}
+}
+
+func TestEditRetriesGiveUp(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{failUploads: 100}
+
+	run(t, f, command("stats", stringOption("username", "starfaiien")))
+
+	if want := "Failed to edit interaction response after multiple attempts."; f.last().content != want {
+		t.Errorf("sent %q, want %q", f.last().content, want)
+	}
+	if n := 100 - f.failUploads; n != 5 {
+		t.Errorf("tried %d uploads, want 5", n)
+	}
+}
+
+func TestLinkCommand(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+
+	run(t, f, command("link", stringOption("username", "starfaiien")))
+
+	if got := userPrefs.Get("user-1").LinkedName; got != "starfaiien" {
+		t.Errorf("linked %q", got)
+	}
+	if !strings.HasPrefix(f.last().content, "Linked to starfaiien.") {
+		t.Errorf("sent %q", f.last().content)
+	}
+}
+
+func TestUnlinkCommand(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+	run(t, f, command("link", stringOption("username", "starfaiien")))
+
+	run(t, f, command("link", stringOption("username", "none")))
+
+	if got := userPrefs.Get("user-1"); got.LinkedUUID != "" || got.LinkedName != "" {
+		t.Errorf("still linked to %q", got.LinkedName)
+	}
+	if !strings.HasPrefix(f.last().content, "Unlinked.") {
+		t.Errorf("sent %q", f.last().content)
+	}
+}
+
+func TestPreferencesClear(t *testing.T) {
+	stubHandlers(t)
+	f := &fakeResponder{}
+	run(t, f, command("link", stringOption("username", "starfaiien")))
+	run(t, f, command("preferences", stringOption("theme", "light"), stringOption("timezone", "Europe/Berlin")))
+
+	run(t, f, command("preferences", stringOption("theme", "none")))
+	if got := userPrefs.Get("user-1"); got.Theme != "" || got.Timezone != "Europe/Berlin" {
+		t.Errorf("clearing the theme left %+v", got)
+	}
+
+	run(t, f, command("preferences", &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  "reset",
+		Type:  discordgo.ApplicationCommandOptionBoolean,
+		Value: true,
+	}, stringOption("number_format", "exact")))
+	if got := userPrefs.Get("user-1"); got != (prefs.Preferences{NumberFormat: "exact"}) {
+		t.Errorf("reset left %+v", got)
+	}
+}
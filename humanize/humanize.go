@@ -0,0 +1,153 @@
+package humanize
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type Mode int
+
+const (
+	Compact Mode = iota // 1.2K, 34.5M
+	Grouped             // 1,234,567
+	Exact               // 1234567
+)
+
+var modeNames = map[string]Mode{
+	"compact": Compact,
+	"grouped": Grouped,
+	"exact":   Exact,
+}
+
+// ParseMode reads a mode by name as stored in user preferences.
+func ParseMode(name string) (Mode, error) {
+	mode, ok := modeNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown number format %q", name)
+	}
+	return mode, nil
+}
+
+// Options decide how numbers are written, the zero value is not useful, start from Default or ForLocale.
+type Options struct {
+	Mode        Mode
+	Precision   int     // most decimals kept, trailing zeros are always dropped
+	Separator   string  // thousands separator, also used by compact mode below CompactFrom
+	Decimal     string  // decimal point
+	CompactFrom float64 // compact mode writes smaller numbers grouped so 3,976 doesn't become 4K
+}
+
+var Default = Options{Mode: Compact, Precision: 1, Separator: ",", Decimal: ".", CompactFrom: 10_000}
+
+// separators by language, anything else gets Default's
+var localeSeparators = map[string][2]string{
+	"de": {".", ","},
+	"es": {".", ","},
+	"it": {".", ","},
+	"nl": {".", ","},
+	"pt": {".", ","},
+	"tr": {".", ","},
+	"fr": {" ", ","},
+	"pl": {" ", ","},
+	"ru": {" ", ","},
+	"sv": {" ", ","},
+}
+
+// ForLocale returns Default with the separators of a locale tag like "de" or "en-GB".
+func ForLocale(tag string) Options {
+	o := Default
+	lang, _, _ := strings.Cut(tag, "-")
+	if seps, ok := localeSeparators[lang]; ok {
+		o.Separator, o.Decimal = seps[0], seps[1]
+	}
+	return o
+}
+
+var units = []string{"", "K", "M", "B", "T"}
+
+// Format writes n according to the options.
+func (o Options) Format(n float64) string {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+
+	sign := ""
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+
+	switch o.Mode {
+	case Compact:
+		if n < o.CompactFrom {
+			return sign + o.grouped(n)
+		}
+		return sign + o.compact(n)
+	case Grouped:
+		return sign + o.grouped(n)
+	default:
+		return sign + o.decimal(strconv.FormatFloat(n, 'f', -1, 64))
+	}
+}
+
+// Int writes n according to the options.
+func (o Options) Int(n int) string {
+	return o.Format(float64(n))
+}
+
+func (o Options) compact(n float64) string {
+	unit := 0
+	for unit < len(units)-1 && n >= 1000 {
+		n /= 1000
+		unit++
+	}
+
+	// 999,950 is 999.95K which rounds to 1000K, that should read 1M
+	rounded := round(n, o.Precision)
+	if rounded >= 1000 && unit < len(units)-1 {
+		rounded = round(n/1000, o.Precision)
+		unit++
+	}
+
+	return o.fixed(rounded) + units[unit]
+}
+
+func (o Options) grouped(n float64) string {
+	s := o.fixed(round(n, o.Precision))
+	whole, frac, _ := strings.Cut(s, o.Decimal)
+
+	var b strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(o.Separator)
+		}
+		b.WriteRune(digit)
+	}
+	if frac != "" {
+		b.WriteString(o.Decimal + frac)
+	}
+	return b.String()
+}
+
+// fixed writes n with up to Precision decimals and no trailing zeros.
+func (o Options) fixed(n float64) string {
+	s := strconv.FormatFloat(n, 'f', max(o.Precision, 0), 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return o.decimal(s)
+}
+
+func (o Options) decimal(s string) string {
+	if o.Decimal == "" || o.Decimal == "." {
+		return s
+	}
+	return strings.Replace(s, ".", o.Decimal, 1)
+}
+
+func round(n float64, precision int) float64 {
+	scale := math.Pow(10, float64(max(precision, 0)))
+	return math.Round(n*scale) / scale
+}
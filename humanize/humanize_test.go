@@ -0,0 +1,65 @@
+package humanize
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	compact := Options{Mode: Compact, Precision: 1, Separator: ",", Decimal: "."}
+	grouped := Options{Mode: Grouped, Precision: 1, Separator: ",", Decimal: "."}
+	exact := Options{Mode: Exact, Decimal: "."}
+
+	tests := []struct {
+		name string
+		opts Options
+		n    float64
+		want string
+	}{
+		{"small compact", compact, 512, "512"},
+		{"zero", compact, 0, "0"},
+		{"fraction", compact, 0.25, "0.3"},
+		{"thousands", compact, 1500, "1.5K"},
+		{"whole thousands", compact, 2000, "2K"},
+		{"rounds into the next unit", compact, 999_950, "1M"},
+		{"stays below the next unit", compact, 999_940, "999.9K"},
+		{"billions", compact, 11_400_000_000, "11.4B"},
+		{"trillions", compact, 2_500_000_000_000, "2.5T"},
+		{"past the last unit", compact, 3_000_000_000_000_000, "3000T"},
+		{"negative compact", compact, -1500, "-1.5K"},
+		{"more precision", Options{Mode: Compact, Precision: 2, Decimal: "."}, 1234, "1.23K"},
+		{"no precision", Options{Mode: Compact, Precision: 0, Decimal: "."}, 1560, "2K"},
+		{"grouped", grouped, 321814, "321,814"},
+		{"grouped small", grouped, 366, "366"},
+		{"grouped exact thousand", grouped, 1000, "1,000"},
+		{"grouped billions", grouped, 11_400_000_000, "11,400,000,000"},
+		{"grouped negative", grouped, -1234567, "-1,234,567"},
+		{"grouped fraction", grouped, 1234.56, "1,234.6"},
+		{"exact", exact, 321814, "321814"},
+		{"exact fraction", exact, 940.75, "940.75"},
+		{"german grouped", Options{Mode: Grouped, Precision: 1, Separator: ".", Decimal: ","}, 1234567.8, "1.234.567,8"},
+		{"german default", ForLocale("de"), 7786, "7.786"},
+		{"german compact", Options{Mode: Compact, Precision: 1, Separator: ".", Decimal: ","}, 1500, "1,5K"},
+		{"french grouped", Options{Mode: Grouped, Separator: " ", Decimal: ","}, 1234567, "1 234 567"},
+		{"english region", ForLocale("en-GB"), 1234567, "1.2M"},
+		{"default keeps small numbers whole", Default, 3976, "3,976"},
+		{"default compacts large numbers", Default, 321814, "321.8K"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.Format(tt.n); got != tt.want {
+				t.Errorf("Format(%v) = %q, want %q", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for name, want := range map[string]Mode{"compact": Compact, "Grouped": Grouped, "exact": Exact} {
+		got, err := ParseMode(name)
+		if err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseMode("roman"); err == nil {
+		t.Error("ParseMode should reject unknown modes")
+	}
+}
@@ -0,0 +1,181 @@
+// Package golden compares rendered images against PNGs committed under
+// testdata. Run the tests with UPDATE_GOLDEN=1 to rewrite them after an
+// intended change. It's an environment variable rather than a flag so
+// `go test ./...` can pass it to every package, golden or not.
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// UpdateEnv is the environment variable that makes Assert rewrite the
+// golden images instead of comparing against them.
+const UpdateEnv = "UPDATE_GOLDEN"
+
+func update() bool {
+	return os.Getenv(UpdateEnv) == "1"
+}
+
+// Options sets how close an image has to be to its golden copy.
+type Options struct {
+	// Threshold is how far apart two pixels may be before they count as
+	// different, from 0 to 1 on a perceptual (YIQ) scale.
+	Threshold float64
+	// MaxDiff is the fraction of pixels allowed to differ, so font
+	// rasterizer or resampling changes don't fail every test.
+	MaxDiff float64
+	// Ignore lists areas that aren't compared.
+	Ignore []image.Rectangle
+	// Dir holds the golden images, "testdata" if empty.
+	Dir string
+}
+
+// Default tolerates antialiasing noise but not a moved or recoloured element.
+var Default = Options{Threshold: 0.1, MaxDiff: 0.001}
+
+// Assert compares img with name.png in opts.Dir. On a mismatch it writes the
+// image it got and a diff next to the golden one as name.actual.png and
+// name.diff.png.
+func Assert(t testing.TB, name string, img image.Image, opts Options) {
+	t.Helper()
+	dir := opts.Dir
+	if dir == "" {
+		dir = "testdata"
+	}
+	path := filepath.Join(dir, name+".png")
+
+	if update() {
+		if err := writePNG(path, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := readPNG(path)
+	if err != nil {
+		t.Fatalf("missing golden image, run the tests with %s=1: %v", UpdateEnv, err)
+	}
+
+	if want.Bounds().Size() != img.Bounds().Size() {
+		t.Errorf("%s is %v, the golden image is %v", name, img.Bounds().Size(), want.Bounds().Size())
+		return
+	}
+
+	diff, n := Diff(want, img, opts)
+	total := img.Bounds().Dx() * img.Bounds().Dy()
+	if float64(n) <= opts.MaxDiff*float64(total) {
+		return
+	}
+
+	actualPath := filepath.Join(dir, name+".actual.png")
+	diffPath := filepath.Join(dir, name+".diff.png")
+	for p, out := range map[string]image.Image{actualPath: img, diffPath: diff} {
+		if err := writePNG(p, out); err != nil {
+			t.Logf("failed to write %s: %v", p, err)
+		}
+	}
+	t.Errorf("%s differs from %s in %d of %d pixels, see %s, run the tests with %s=1 if the change is intended",
+		name, path, n, total, diffPath, UpdateEnv)
+}
+
+// Diff counts the pixels of got that differ from want and returns an image
+// with them in red over a faded copy of want. Both must be the same size.
+func Diff(want, got image.Image, opts Options) (*image.NRGBA, int) {
+	a, b := toNRGBA(want), toNRGBA(got)
+	out := image.NewNRGBA(a.Bounds())
+
+	// pixelmatch's limit for YIQ distance, 35215 is the largest possible delta
+	limit := 35215 * opts.Threshold * opts.Threshold
+
+	n := 0
+	for y := 0; y < a.Bounds().Dy(); y++ {
+		for x := 0; x < a.Bounds().Dx(); x++ {
+			ca, cb := a.NRGBAAt(x, y), b.NRGBAAt(x, y)
+			ignored := ignore(opts.Ignore, x, y)
+			if !ignored && delta(ca, cb) > limit {
+				n++
+				out.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+				continue
+			}
+			grey := uint8(255 - (255-luma(ca))/4)
+			if ignored {
+				out.SetNRGBA(x, y, color.NRGBA{R: grey / 2, G: grey / 2, B: grey, A: 255})
+				continue
+			}
+			out.SetNRGBA(x, y, color.NRGBA{R: grey, G: grey, B: grey, A: 255})
+		}
+	}
+	return out, n
+}
+
+func ignore(areas []image.Rectangle, x, y int) bool {
+	for _, r := range areas {
+		if image.Pt(x, y).In(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// delta is the squared YIQ distance of two colours blended over white.
+func delta(a, b color.NRGBA) float64 {
+	ya, ia, qa := yiq(a)
+	yb, ib, qb := yiq(b)
+	dy, di, dq := ya-yb, ia-ib, qa-qb
+	return 0.5053*dy*dy + 0.299*di*di + 0.1957*dq*dq
+}
+
+func yiq(c color.NRGBA) (float64, float64, float64) {
+	alpha := float64(c.A) / 255
+	blend := func(v uint8) float64 { return 255 + (float64(v)-255)*alpha }
+	r, g, b := blend(c.R), blend(c.G), blend(c.B)
+	return r*0.29889531 + g*0.58662247 + b*0.11448223,
+		r*0.59597799 - g*0.27417610 - b*0.32180189,
+		r*0.21147017 - g*0.52261711 + b*0.31114694
+}
+
+func luma(c color.NRGBA) uint8 {
+	y, _, _ := yiq(c)
+	return uint8(max(0, min(255, y)))
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	n := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(n, n.Bounds(), img, img.Bounds().Min, draw.Src)
+	return n
+}
+
+func readPNG(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	return img, nil
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,54 @@
+package golden
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func solid(c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestDiff(t *testing.T) {
+	grey := color.NRGBA{R: 128, G: 128, B: 128, A: 255}
+	want := solid(grey)
+
+	nearly := solid(grey)
+	nearly.SetNRGBA(3, 3, color.NRGBA{R: 131, G: 127, B: 129, A: 255})
+	if _, n := Diff(want, nearly, Default); n != 0 {
+		t.Errorf("a barely changed pixel counted as %d differences", n)
+	}
+
+	changed := solid(grey)
+	changed.SetNRGBA(3, 3, color.NRGBA{R: 255, A: 255})
+	if _, n := Diff(want, changed, Default); n != 1 {
+		t.Errorf("a red pixel counted as %d differences, want 1", n)
+	}
+
+	opts := Default
+	opts.Ignore = []image.Rectangle{image.Rect(2, 2, 5, 5)}
+	if _, n := Diff(want, changed, opts); n != 0 {
+		t.Errorf("an ignored pixel counted as %d differences", n)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	img := solid(color.NRGBA{R: 40, G: 80, B: 120, A: 255})
+
+	t.Setenv(UpdateEnv, "1")
+	Assert(t, "solid", img, Options{Dir: dir})
+	if _, err := os.Stat(filepath.Join(dir, "solid.png")); err != nil {
+		t.Fatalf("the golden image wasn't written: %v", err)
+	}
+
+	t.Setenv(UpdateEnv, "")
+	Assert(t, "solid", img, Options{Dir: dir})
+}
@@ -1,18 +1,29 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"wynn_bot/assets"
+	"wynn_bot/canvas"
 	"wynn_bot/chartings"
+	"wynn_bot/humanize"
+	"wynn_bot/metrics"
 	"wynn_bot/models"
+	"wynn_bot/prefs"
 	"wynn_bot/statscard"
+	"wynn_bot/timefmt"
+	"wynn_bot/wynnapi"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/joho/godotenv"
@@ -32,12 +43,12 @@ func parseOptions(options []*discordgo.ApplicationCommandInteractionDataOption)
 	return
 }
 
-// func interactionAuthor(i *discordgo.Interaction) *discordgo.User {
-// 	if i.Member != nil {
-// 		return i.Member.User
-// 	}
-// 	return i.User
-// }
+func interactionAuthor(i *discordgo.Interaction) *discordgo.User {
+	if i.Member != nil {
+		return i.Member.User
+	}
+	return i.User
+}
 
 func loadEnv(key string, verbose bool) (string, error) {
 	err := godotenv.Load("secrets.env")
@@ -64,12 +75,159 @@ var commands = []*discordgo.ApplicationCommand{
 		Options: []*discordgo.ApplicationCommandOption{
 			{
 				Name:        "username",
-				Description: "The player's username or uuid.",
+				Description: "The player's username or uuid, defaults to your linked account.",
+				Type:        discordgo.ApplicationCommandOptionString,
+			},
+			{
+				Name:        "theme",
+				Description: "The card theme, overrides your saved preference.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices:     themeChoices(),
+			},
+			{
+				Name:        "format",
+				Description: "An image card, a smaller compact or an animated one, or the stats as text for small screens.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "image", Value: formatImage},
+					{Name: "compact image", Value: formatCompact},
+					{Name: "animated", Value: formatAnimated},
+					{Name: "embed", Value: formatEmbed},
+				},
+			},
+		},
+	},
+	{
+		Name:        "raids",
+		Description: "Displays a player's raid completions and leaderboard positions.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "username",
+				Description: "The player's username or uuid, defaults to your linked account.",
+				Type:        discordgo.ApplicationCommandOptionString,
+			},
+			{
+				Name:        "theme",
+				Description: "The card theme, overrides your saved preference.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices:     themeChoices(),
+			},
+		},
+	},
+	{
+		Name:        "dungeons",
+		Description: "Displays a player's dungeon completions, corrupted ones included.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "username",
+				Description: "The player's username or uuid, defaults to your linked account.",
+				Type:        discordgo.ApplicationCommandOptionString,
+			},
+			{
+				Name:        "theme",
+				Description: "The card theme, overrides your saved preference.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices:     themeChoices(),
+			},
+		},
+	},
+	{
+		Name:        "professions",
+		Description: "Displays a player's profession levels, for one character or the best of each.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "username",
+				Description: "The player's username or uuid, defaults to your linked account.",
+				Type:        discordgo.ApplicationCommandOptionString,
+			},
+			{
+				Name:        "character",
+				Description: "A character by uuid, nickname or class, defaults to the best character for each profession.",
+				Type:        discordgo.ApplicationCommandOptionString,
+			},
+			{
+				Name:        "theme",
+				Description: "The card theme, overrides your saved preference.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices:     themeChoices(),
+			},
+		},
+	},
+	{
+		Name:        "link",
+		Description: "Links your discord account to a wynncraft account.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "username",
+				Description: "Your wynncraft username or uuid, or none to unlink.",
 				Type:        discordgo.ApplicationCommandOptionString,
 				Required:    true,
 			},
 		},
 	},
+	{
+		Name:        "preferences",
+		Description: "Saves your default settings for other commands.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "theme",
+				Description: "The card theme to use when none is given.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices:     append(themeChoices(), clearChoice),
+			},
+			{
+				Name:        "character",
+				Description: "Your default character, by uuid, nickname or class, or none to clear.",
+				Type:        discordgo.ApplicationCommandOptionString,
+			},
+			{
+				Name:        "timezone",
+				Description: "Your timezone, e.g. Europe/Berlin or America/New_York, or none to clear.",
+				Type:        discordgo.ApplicationCommandOptionString,
+			},
+			{
+				Name:        "number_format",
+				Description: "How numbers are written on cards.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices:     append(numberFormatChoices(), clearChoice),
+			},
+			{
+				Name:        "reset",
+				Description: "Forget everything saved, including the linked account, before saving the rest.",
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+			},
+		},
+	},
+	{
+		Name:        "banner",
+		Description: "Displays a guild's banner.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "guild",
+				Description: "The guild's name or prefix.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "guild",
+		Description: "Displays a guild's stats beside its banner.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "guild",
+				Description: "The guild's name or prefix.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Required:    true,
+			},
+			{
+				Name:        "theme",
+				Description: "The card theme, overrides your saved preference.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices:     themeChoices(),
+			},
+		},
+	},
 	{
 		Name:        "charttest",
 		Description: "Testing command for the charting function",
@@ -84,93 +242,263 @@ var commands = []*discordgo.ApplicationCommand{
 	},
 }
 
-func getPlayerStat(s *discordgo.Session, i *discordgo.InteractionCreate, opts optionMap) {
+func getPlayerStat(s responder, i *discordgo.InteractionCreate, opts optionMap) {
+	const waiting = "Generating stats card, please wait..."
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: "Generating stats card, please wait...",
+			Content: waiting,
 		},
 	})
 
 	if err != nil {
-		log.Panicf("could not respond to interaction: %s", err)
+		log.Printf("Could not respond to interaction: %s", err)
+		countCommand(i, outcomeFailed)
 		return
 	}
 
-	api_URL := "https://api.wynncraft.com/v3/player/%s?fullResult"
-	username := opts["username"].StringValue()
+	enqueueRender(s, i, waiting, func(ctx context.Context) {
+		drawPlayerStat(ctx, s, i, opts)
+	})
+}
 
-	url := fmt.Sprintf(api_URL, username)
-	resp, err := http.Get(url)
+// lookupPlayer fetches the player the command names, or the author's linked
+// account. When it can't, it says why in the response, counts the outcome
+// and returns false.
+func lookupPlayer(ctx context.Context, s responder, i *discordgo.InteractionCreate, opts optionMap) (models.PlayerData, bool) {
+	username, err := resolveUsername(i.Interaction, opts)
 	if err != nil {
-		log.Printf("Failed to access URL: %s", err)
 		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPointer("Failed to access the player data URL."),
+			Content: stringPointer("No username given and no linked account, use /link to link yours."),
 		})
-		return
+		countCommand(i, outcomeInvalid)
+		return models.PlayerData{}, false
 	}
-	defer resp.Body.Close()
 
-	var playerData models.PlayerData
-	err = json.NewDecoder(resp.Body).Decode(&playerData)
-	if err != nil {
+	playerData, err := getPlayer(ctx, username)
+	if errors.Is(err, wynnapi.ErrDecode) {
 		log.Printf("Failed to decode JSON: %s", err)
 		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: stringPointer("Failed to decode player data JSON."),
 		})
-		return
+		countCommand(i, outcomeAPIError)
+		return models.PlayerData{}, false
+	} else if errors.Is(err, wynnapi.ErrNotFound) {
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer(fmt.Sprintf("Player %s was not found.", username)),
+		})
+		countCommand(i, outcomeNotFound)
+		return models.PlayerData{}, false
+	} else if err != nil {
+		log.Printf("Failed to access URL: %s", err)
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer("Failed to access the player data URL."),
+		})
+		countCommand(i, outcomeAPIError)
+		return models.PlayerData{}, false
 	}
+	return playerData, true
+}
 
-	// Generate the stats card
-	imagePath := "statcard.png"
-	err = statscard.CreateStatsCard(playerData, "statscard/images", imagePath)
+// cardOptions is the author's saved preferences for a card, an explicit
+// theme option winning over the saved one.
+func cardOptions(i *discordgo.InteractionCreate, opts optionMap) statscard.Options {
+	userPref := userPrefs.Get(interactionAuthor(i.Interaction).ID)
+	themeName := userPref.Theme
+	if opt, ok := opts["theme"]; ok {
+		themeName = opt.StringValue()
+	}
+	theme, err := statscard.GetTheme(themeName)
 	if err != nil {
-		log.Printf("Failed to generate stats card: %s", err)
-		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPointer("Failed to generate stats card."),
-		})
-		return
+		log.Printf("Falling back to the default theme: %s", err)
+		theme, _ = statscard.GetTheme("")
 	}
 
-	// Open the generated image
-	file, err := os.Open("statscard/images/statcard.png")
+	timeFormat, err := timefmt.New(userPref.Timezone, string(i.Locale))
 	if err != nil {
-		log.Printf("Failed to open stats card: %s", err)
-		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPointer("Failed to open the generated stats card."),
-		})
+		log.Printf("Falling back to UTC: %s", err)
+		timeFormat = timefmt.Default()
+	}
+
+	numbers := humanize.ForLocale(string(i.Locale))
+	if userPref.NumberFormat != "" {
+		if mode, err := humanize.ParseMode(userPref.NumberFormat); err == nil {
+			numbers.Mode = mode
+		}
+	}
+	return statscard.Options{Theme: theme, Time: timeFormat, Numbers: numbers}
+}
+
+// cardAnimation is how animated cards move, less the rings that levelled
+// up which are the player's own.
+var cardAnimation statscard.Animation
+
+// seenLevels is each player's class levels as of their last /stats, so an
+// animated card can sweep just the rings that went up since.
+var seenLevels = &levelHistory{levels: map[string]map[string]int{}}
+
+type levelHistory struct {
+	mu     sync.Mutex
+	levels map[string]map[string]int // by player uuid
+}
+
+// update remembers data's class levels and returns the ones that went up
+// since the player was last seen, nil if none did or they're new.
+func (h *levelHistory) update(data models.PlayerData) map[string]int {
+	levels := statscard.ClassLevels(data)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	before, ok := h.levels[data.UUID]
+	h.levels[data.UUID] = levels
+	if !ok {
+		return nil
+	}
+	return statscard.LevelledUp(before, levels)
+}
+
+// drawPlayerStat fetches the player and sends their card, run from the render queue.
+func drawPlayerStat(ctx context.Context, s responder, i *discordgo.InteractionCreate, opts optionMap) {
+	playerData, ok := lookupPlayer(ctx, s, i, opts)
+	if !ok {
+		return
+	}
+	username := playerData.Username
+	cardOpts := cardOptions(i, opts)
+
+	var guildData *models.GuildData
+	if playerData.Guild != nil {
+		guild, err := getGuild(ctx, playerData.Guild.Name)
+		if err != nil {
+			log.Printf("Failed to fetch guild, drawing the card without it: %s", err)
+		} else {
+			guildData = &guild
+		}
+	}
+
+	// the donuts start at an angle picked per player, so a card only changes when the stats do
+	cardOpts.Chart = statscard.ChartOptions{Seed: statscard.SeedFor(playerData.UUID)}
+	cardOpts.Avatars = avatars
+	anim := cardAnimation
+	anim.LevelledUp = seenLevels.update(playerData)
+	cardOpts.Animation = &anim
+
+	encoding := canvas.FormatPNG
+	if opt, ok := opts["format"]; ok {
+		switch opt.StringValue() {
+		case formatEmbed:
+			sendStatsEmbed(s, i, playerData, guildData, cardOpts, "")
+			return
+		case formatCompact:
+			encoding = canvas.FormatPNG8
+		case formatAnimated:
+			encoding = canvas.FormatGIF
+		}
+	}
+
+	start := time.Now()
+	var buffer bytes.Buffer
+	err := encodeStatsCard(ctx, &buffer, encoding, playerData, guildData, cardOpts)
+	if ctx.Err() != nil {
+		dropRender(ctx, s, i, "the card for "+username)
+		return
+	} else if err != nil {
+		log.Printf("Failed to generate stats card, sending the stats as text: %s", err)
+		sendStatsEmbed(s, i, playerData, guildData, cardOpts, cardFailedNote)
+		return
+	}
+	card := "stats"
+	if encoding == canvas.FormatGIF {
+		card = "stats_animated"
+	}
+	renderDuration.Observe(time.Since(start).Seconds(), card)
+	if ctx.Err() != nil {
+		dropRender(ctx, s, i, "the card for "+username)
 		return
 	}
-	defer file.Close()
 
-	// Retry logic for editing the response
-	maxRetries := 5
-	for attempts := 0; attempts < maxRetries; attempts++ {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPointer(""),
+	err = editWithRetries(s, i, func() *discordgo.WebhookEdit {
+		return &discordgo.WebhookEdit{
+			Content: stringPointer(playerSummary(playerData)),
 			Files: []*discordgo.File{
 				{
-					Name:   "statcard.png",
-					Reader: file,
+					Name:   "statcard" + encoding.Ext(),
+					Reader: bytes.NewReader(buffer.Bytes()),
 				},
 			},
-		})
-		if err == nil {
-			break // Success
 		}
-		log.Printf("Retry %d: Failed to edit interaction response with image: %s", attempts+1, err)
-		time.Sleep(time.Second) // Wait before retrying
+	})
+	if err != nil {
+		log.Printf("Failed to edit interaction response after retries: %s", err)
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer("Failed to edit interaction response after multiple attempts."),
+		})
+		countCommand(i, outcomeFailed)
+		return
 	}
+	countCommand(i, outcomeOK)
+}
 
+// /stats format choices, the image is the default. The compact image has
+// its colours reduced to a palette, a third of the upload.
+const (
+	formatImage    = "image"
+	formatCompact  = "compact"
+	formatAnimated = "animated"
+	formatEmbed    = "embed"
+)
+
+// encodeStatsCard draws the card into w as f. Stills go through
+// renderStatsCard, an animation draws each frame itself.
+func encodeStatsCard(ctx context.Context, w io.Writer, f canvas.Format, data models.PlayerData, guild *models.GuildData, opts statscard.Options) error {
+	if f == canvas.FormatGIF {
+		return statscard.WriteStatsCard(ctx, w, f, data, guild, opts)
+	}
+	card, err := renderStatsCard(ctx, data, guild, opts)
+	if err != nil {
+		return err
+	}
+	return canvas.EncodeImage(w, card, f)
+}
+
+// sent above the embed when it stands in for a card that couldn't be drawn
+const cardFailedNote = "Couldn't draw the card, here are the stats as text."
+
+// sendStatsEmbed answers with the stats as an embed, for players who asked
+// for text and as the fallback when the card fails. note goes above it.
+func sendStatsEmbed(s responder, i *discordgo.InteractionCreate, data models.PlayerData, guild *models.GuildData, opts statscard.Options, note string) {
+	embed := statscard.Embed(data, guild, opts)
+	badge, err := statscard.RankBadgePNG(data)
+	if err != nil {
+		log.Printf("Sending the embed without a rank badge: %s", err)
+		embed.Thumbnail = nil
+	}
+
+	err = editWithRetries(s, i, func() *discordgo.WebhookEdit {
+		edit := &discordgo.WebhookEdit{
+			Content: stringPointer(note),
+			Embeds:  &[]*discordgo.MessageEmbed{embed},
+		}
+		if badge != nil {
+			edit.Files = []*discordgo.File{{Name: statscard.BadgeName, Reader: bytes.NewReader(badge)}}
+		}
+		return edit
+	})
 	if err != nil {
 		log.Printf("Failed to edit interaction response after retries: %s", err)
 		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: stringPointer("Failed to edit interaction response after multiple attempts."),
 		})
+		countCommand(i, outcomeFailed)
+		return
+	}
+	if note != "" {
+		countCommand(i, outcomeFallback)
+		return
 	}
+	countCommand(i, outcomeOK)
 }
 
-func ChartTest(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func ChartTest(s responder, i *discordgo.InteractionCreate) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -194,7 +522,9 @@ func ChartTest(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		Desc:     "This is a test chart",
 	}
 
-	buffer, err := chartings.Render(data)
+	ctx, cancel := interactionContext(i)
+	defer cancel()
+	buffer, err := chartings.Render(ctx, data)
 	if err != nil {
 		log.Printf("Failed to render chart: %s", err)
 		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
@@ -203,25 +533,17 @@ func ChartTest(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	// Retry logic for editing the response
-	maxRetries := 5
-	for attempts := 0; attempts < maxRetries; attempts++ {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+	err = editWithRetries(s, i, func() *discordgo.WebhookEdit {
+		return &discordgo.WebhookEdit{
 			Content: stringPointer("Chart generated!"),
 			Files: []*discordgo.File{
 				{
 					Name:   "chart.png",
-					Reader: buffer,
+					Reader: bytes.NewReader(buffer.Bytes()),
 				},
 			},
-		})
-		if err == nil {
-			break // Success
 		}
-		log.Printf("Retry %d: Failed to edit interaction response with image: %s", attempts+1, err)
-		time.Sleep(time.Second) // Wait before retrying
-	}
-
+	})
 	if err != nil {
 		log.Printf("Failed to edit interaction response after retries: %s", err)
 		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
@@ -230,6 +552,21 @@ func ChartTest(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 }
 
+// playerSummary is the text sent alongside a card, discord renders the
+// timestamps in each reader's own timezone.
+func playerSummary(data models.PlayerData) string {
+	summary := "**" + data.Username + "**"
+	if t, err := timefmt.Parse(data.FirstJoin); err == nil {
+		summary += " · first joined " + timefmt.Discord(t, timefmt.LongDate)
+	}
+	if data.Online {
+		summary += " · online now"
+	} else if t, err := timefmt.Parse(data.LastJoin); err == nil {
+		summary += " · last seen " + timefmt.Discord(t, timefmt.RelativeTime)
+	}
+	return summary
+}
+
 func stringPointer(s string) *string {
 	return &s
 }
@@ -247,7 +584,49 @@ func stringPointer(s string) *string {
 // 	}
 // }
 
+// handleCommand runs the handler for a slash command.
+func handleCommand(s responder, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	if data.Name == "stats" {
+		getPlayerStat(s, i, parseOptions(data.Options))
+	} else if data.Name == "raids" {
+		getPage(s, i, parseOptions(data.Options), raidsPage)
+	} else if data.Name == "dungeons" {
+		getPage(s, i, parseOptions(data.Options), dungeonsPage)
+	} else if data.Name == "professions" {
+		opts := parseOptions(data.Options)
+		getPage(s, i, opts, professionsPage(i, opts))
+	} else if data.Name == "banner" {
+		getGuildImage(s, i, parseOptions(data.Options), bannerImage)
+	} else if data.Name == "guild" {
+		getGuildImage(s, i, parseOptions(data.Options), guildCardImage)
+	} else if data.Name == "link" {
+		linkAccount(s, i, parseOptions(data.Options))
+	} else if data.Name == "preferences" {
+		setPreferences(s, i, parseOptions(data.Options))
+	} else if data.Name == "charttest" {
+
+	}
+}
+
+// onInteraction runs slash commands. discordgo only calls handlers whose
+// type is one of its event funcs, so s has to stay a *discordgo.Session
+// rather than a responder.
+func (l *lifecycle) onInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	l.handle(i, func() { handleCommand(s, i) })
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "assets" {
+		os.Exit(runAssets(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServe(os.Args[2:]))
+	}
+
 	// load .env file
 	token, err := loadEnv("DISCORD_TOKEN", true)
 	if err != nil {
@@ -262,27 +641,67 @@ func main() {
 		fmt.Println("error retrieving token: ", err)
 	}
 
-	// start a discord session
-	session, err := discordgo.New("Bot " + token)
+	userPrefs, err = prefs.Open(preferencesPath)
 	if err != nil {
-		fmt.Println("error creating discord session:", err)
+		fmt.Println("error loading preferences:", err)
 		return
 	}
 
-	// add command handler
-	session.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if i.Type != discordgo.InteractionApplicationCommand {
+	var flush []func() error
+	if err := wynnapi.LoadCache(apiCachePath); err != nil {
+		log.Printf("Starting with an empty api cache: %s", err)
+	}
+	flush = append(flush, func() error { return wynnapi.SaveCache(apiCachePath) })
+	if path, err := loadEnv("LOG_FILE", false); err == nil {
+		closeLog, err := logToFile(path)
+		if err != nil {
+			fmt.Println("error opening log file:", err)
 			return
 		}
+		flush = append(flush, closeLog)
+	}
+
+	// cancelled once shutdown has given in-flight commands their grace period
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rootCtx = ctx
 
-		data := i.ApplicationCommandData()
-		if data.Name == "stats" {
-			getPlayerStat(s, i, parseOptions(data.Options))
-		} else if data.Name == "charttest" {
+	renders = newRenderQueue()
 
+	if addr, err := loadEnv("HTTP_ADDR", false); err == nil {
+		startServer(ctx, addr)
+	}
+
+	// start a discord session
+	session, err := discordgo.New("Bot " + token)
+	if err != nil {
+		fmt.Println("error creating discord session:", err)
+		return
+	}
+
+	bot := &lifecycle{session: session, renders: renders, cancel: cancel, flush: flush}
+
+	var registered atomic.Bool
+	if addr, err := loadEnv("ADMIN_ADDR", false); err == nil {
+		for _, path := range assets.Verify(assets.DefaultConfig) {
+			log.Printf("Missing asset, the cards will draw a fallback: %s", path)
+		}
+		// only what the cards can't do without keeps the bot from being ready
+		missing := assets.VerifyRequired()
+		for _, path := range missing {
+			log.Printf("Missing asset: %s", path)
 		}
+		admin := adminHandler(gatewayHealth(session), readiness(&registered, missing, bot), metrics.Default)
+		go func() {
+			log.Printf("serving health and metrics on %s", addr)
+			if err := listenAndServe(ctx, addr, admin); err != nil {
+				log.Printf("admin server stopped: %s", err)
+			}
+		}()
+	}
 
-	})
+	// add command handler
+	session.AddHandler(bot.onInteraction)
 
 	// Open a connection to Discord
 	err = session.Open()
@@ -299,6 +718,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("could not register commands: %s", err)
 	}
+	registered.Store(true)
 
 	fmt.Println("Bot is now running. Press CTRL+C to exit.")
 
@@ -307,7 +727,11 @@ func main() {
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 
-	// Cleanly close the Discord session
-	session.Close()
+	// Let the commands already running finish before closing the Discord session
+	grace := shutdownGrace()
+	log.Printf("Shutting down, waiting up to %s for commands in flight", grace)
+	if err := bot.shutdown(grace); err != nil {
+		log.Printf("Shutdown: %s", err)
+	}
 
 }
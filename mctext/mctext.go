@@ -0,0 +1,221 @@
+package mctext
+
+import (
+	"hash/fnv"
+	"image/color"
+	"math/rand"
+	"strings"
+
+	"wynn_bot/canvas"
+)
+
+// Segment is a run of text sharing one format.
+type Segment struct {
+	Text       string
+	Color      color.Color // nil takes the line's gradient
+	Bold       bool
+	Obfuscated bool
+}
+
+// Style applies to a whole line.
+type Style struct {
+	// From and To colour segments without their own colour, blended per glyph
+	// across the line. To may be nil for a flat colour.
+	From, To color.Color
+	Shadow   bool
+
+	// Frame picks which random glyphs obfuscated text shows, the same frame
+	// always draws the same glyphs so renders stay reproducible.
+	Frame int
+}
+
+// legacy formatting codes, §a
+var codeColors = map[rune]color.RGBA{
+	'0': {0, 0, 0, 255},
+	'1': {0, 0, 170, 255},
+	'2': {0, 170, 0, 255},
+	'3': {0, 170, 170, 255},
+	'4': {170, 0, 0, 255},
+	'5': {170, 0, 170, 255},
+	'6': {255, 170, 0, 255},
+	'7': {170, 170, 170, 255},
+	'8': {85, 85, 85, 255},
+	'9': {85, 85, 255, 255},
+	'a': {85, 255, 85, 255},
+	'b': {85, 255, 255, 255},
+	'c': {255, 85, 85, 255},
+	'd': {255, 85, 255, 255},
+	'e': {255, 255, 85, 255},
+	'f': {255, 255, 255, 255},
+}
+
+// Gold is minecraft's §6.
+var Gold = codeColors['6']
+
+// Parse splits text with legacy § formatting codes into segments. Colour
+// codes reset bold and obfuscation like they do in game, §r resets
+// everything, and codes it doesn't know are dropped, as is a § that ends
+// the text. & is left alone, only chat plugins treat it as a code and a
+// name may well contain one.
+func Parse(text string) []Segment {
+	var segments []Segment
+	current := Segment{}
+	var b strings.Builder
+
+	flush := func() {
+		if b.Len() > 0 {
+			current.Text = b.String()
+			segments = append(segments, current)
+			b.Reset()
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '§' {
+			if i+1 == len(runes) {
+				break
+			}
+			i++
+			code := toLower(runes[i])
+			if c, ok := codeColors[code]; ok {
+				flush()
+				current = Segment{Color: c}
+				continue
+			}
+			switch code {
+			case 'l':
+				flush()
+				current.Bold = true
+			case 'k':
+				flush()
+				current.Obfuscated = true
+			case 'r':
+				flush()
+				current = Segment{}
+			}
+			// strikethrough, underline, italic and unknown codes aren't drawn
+			continue
+		}
+		b.WriteRune(r)
+	}
+	flush()
+	return segments
+}
+
+func toLower(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+	return r
+}
+
+// glyph is one rune ready to draw.
+type glyph struct {
+	text  string
+	color color.Color
+	bold  bool
+	x     float64 // offset from the start of the line
+}
+
+const obfuscatedPool = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// pixel is the size of one font pixel, the offset for bold and shadows.
+func pixel(dc canvas.Canvas) float64 {
+	return max(1, dc.FontHeight()/8)
+}
+
+// layout places each glyph with the context's current font face.
+func layout(dc canvas.Canvas, segments []Segment, style Style) ([]glyph, float64) {
+	var seedText strings.Builder
+	for _, seg := range segments {
+		seedText.WriteString(seg.Text)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(seedText.String()))
+	rng := rand.New(rand.NewSource(int64(h.Sum64()) + int64(style.Frame)))
+
+	px := pixel(dc)
+	var glyphs []glyph
+	x := 0.0
+	for _, seg := range segments {
+		for _, r := range seg.Text {
+			text := string(r)
+			if seg.Obfuscated && r != ' ' {
+				text = string(obfuscatedPool[rng.Intn(len(obfuscatedPool))])
+			}
+			glyphs = append(glyphs, glyph{text: text, color: seg.Color, bold: seg.Bold, x: x})
+
+			advance, _ := dc.MeasureString(text)
+			if seg.Bold {
+				advance += px
+			}
+			x += advance
+		}
+	}
+	return glyphs, x
+}
+
+// Measure returns the width and height the line takes with the context's current font face.
+func Measure(dc canvas.Canvas, segments []Segment, style Style) (float64, float64) {
+	_, w := layout(dc, segments, style)
+	return w, dc.FontHeight()
+}
+
+// Draw writes the line at x, y anchored like gg's DrawStringAnchored, and
+// returns its width so callers can place things after it.
+func Draw(dc canvas.Canvas, segments []Segment, style Style, x, y, ax, ay float64) float64 {
+	glyphs, w := layout(dc, segments, style)
+	h := dc.FontHeight()
+	x -= ax * w
+	y += ay * h
+
+	px := pixel(dc)
+	colorAt := func(g glyph) color.Color {
+		if g.color != nil {
+			return g.color
+		}
+		if style.From == nil {
+			return color.White
+		}
+		if style.To == nil || w == 0 {
+			return style.From
+		}
+		return lerp(style.From, style.To, g.x/w)
+	}
+
+	if style.Shadow {
+		for _, g := range glyphs {
+			dc.SetColor(shadowOf(colorAt(g)))
+			drawGlyph(dc, g, x+px, y+px, px)
+		}
+	}
+	for _, g := range glyphs {
+		dc.SetColor(colorAt(g))
+		drawGlyph(dc, g, x, y, px)
+	}
+	return w
+}
+
+func drawGlyph(dc canvas.Canvas, g glyph, x, y, px float64) {
+	dc.DrawString(g.text, x+g.x, y)
+	if g.bold {
+		dc.DrawString(g.text, x+g.x+px, y)
+	}
+}
+
+// shadowOf darkens to a quarter like the game's text shadow.
+func shadowOf(c color.Color) color.Color {
+	r, g, b, a := c.RGBA()
+	return color.RGBA{R: uint8(r >> 10), G: uint8(g >> 10), B: uint8(b >> 10), A: uint8(a >> 8)}
+}
+
+func lerp(from, to color.Color, t float64) color.Color {
+	r1, g1, b1, a1 := from.RGBA()
+	r2, g2, b2, a2 := to.RGBA()
+	mix := func(a, b uint32) uint8 {
+		return uint8((float64(a)*(1-t) + float64(b)*t) / 257)
+	}
+	return color.RGBA{R: mix(r1, r2), G: mix(g1, g2), B: mix(b1, b2), A: mix(a1, a2)}
+}
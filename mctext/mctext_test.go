@@ -0,0 +1,69 @@
+package mctext
+
+import (
+	"image/color"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/fogleman/gg"
+)
+
+func TestParse(t *testing.T) {
+	green, red := codeColors['a'], codeColors['c']
+	tests := []struct {
+		name string
+		text string
+		want []Segment
+	}{
+		{"plain", "Starfall", []Segment{{Text: "Starfall"}}},
+		{"colour", "§aStar§cfall", []Segment{{Text: "Star", Color: green}, {Text: "fall", Color: red}}},
+		{"uppercase code", "§AStar", []Segment{{Text: "Star", Color: green}}},
+		{"bold and obfuscated", "§l§kStar", []Segment{{Text: "Star", Bold: true, Obfuscated: true}}},
+		{"colour resets bold", "§lStar§afall", []Segment{{Text: "Star", Bold: true}, {Text: "fall", Color: green}}},
+		{"reset", "§a§lStar§rfall", []Segment{{Text: "Star", Color: green, Bold: true}, {Text: "fall"}}},
+		{"undrawn and unknown codes", "§oSt§zar", []Segment{{Text: "Star"}}},
+		{"trailing lone prefix", "Star§", []Segment{{Text: "Star"}}},
+		{"ampersand is text", "R&D &a team", []Segment{{Text: "R&D &a team"}}},
+		{"empty", "§a", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	dc := gg.NewContext(10, 10)
+	if err := dc.LoadFontFace("../statscard/fonts/minecraft.ttf", 16); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := dc.MeasureString("a")
+	b, _ := dc.MeasureString("b")
+
+	w, h := Measure(dc, Parse("§aa§cb"), Style{})
+	if w != a+b || h != dc.FontHeight() {
+		t.Errorf("measured %v by %v, want %v by %v", w, h, a+b, dc.FontHeight())
+	}
+	// bold draws each glyph a font pixel wider
+	bold, _ := Measure(dc, Parse("§lab"), Style{})
+	if want := a + b + 2*pixel(dc); math.Abs(bold-want) > 1e-9 {
+		t.Errorf("bold measured %v, want %v", bold, want)
+	}
+	// obfuscated glyphs are picked the same way every time
+	secret := Parse("§kab")
+	first, _ := Measure(dc, secret, Style{Frame: 3})
+	if again, _ := Measure(dc, secret, Style{Frame: 3}); again != first {
+		t.Errorf("the same frame measured %v then %v", first, again)
+	}
+}
+
+func TestLerp(t *testing.T) {
+	black, white := color.RGBA{A: 255}, color.RGBA{255, 255, 255, 255}
+	if got := lerp(black, white, 0.5); got != (color.RGBA{127, 127, 127, 255}) {
+		t.Errorf("halfway is %v", got)
+	}
+}
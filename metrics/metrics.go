@@ -0,0 +1,237 @@
+// Package metrics keeps the counters the admin server exposes, written out
+// in the Prometheus text format. It covers the few kinds the bot uses rather
+// than pulling in the full client.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metrics in the order they were added.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// Default is where the packages of the bot register their metrics.
+var Default = &Registry{}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+func (r *Registry) add(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the text exposition format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(buf)
+	}
+	return buf.Flush()
+}
+
+// Handler serves the registry for a Prometheus scrape.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// series keeps one value per combination of label values.
+type series[T any] struct {
+	name, help, kind string
+	labels           []string
+
+	mu     sync.Mutex
+	keys   []string // first seen order, so the output is stable
+	values map[string]*T
+}
+
+func newSeries[T any](name, help, kind string, labels []string) series[T] {
+	return series[T]{name: name, help: help, kind: kind, labels: labels, values: map[string]*T{}}
+}
+
+// with returns the value for the label values, creating it with fresh if
+// it's new. The caller holds mu.
+func (s *series[T]) with(values []string, fresh func() *T) *T {
+	if len(values) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", s.name, len(s.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v, ok := s.values[key]
+	if !ok {
+		v = fresh()
+		s.values[key] = v
+		s.keys = append(s.keys, key)
+	}
+	return v
+}
+
+func (s *series[T]) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", s.name, s.help, s.name, s.kind)
+}
+
+// labelPairs formats the label values stored under key, with extra pairs
+// appended, e.g. {endpoint="player",le="0.5"}.
+func (s *series[T]) labelPairs(key string, extra ...string) string {
+	var pairs []string
+	if len(s.labels) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, s.labels[i]+"="+strconv.Quote(value))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter counts events, split by its labels.
+type Counter struct {
+	series[float64]
+}
+
+// NewCounter adds a counter to r.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newSeries[float64](name, help, "counter", labels)}
+	r.add(c)
+	return c
+}
+
+// Inc adds one for the label values, given in the order the labels were.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds n for the label values.
+func (c *Counter) Add(n float64, values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.with(values, func() *float64 { return new(float64) }) += n
+}
+
+// Value is the count for the label values so far.
+func (c *Counter) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.values[strings.Join(values, "\xff")]; ok {
+		return *v
+	}
+	return 0
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w)
+	for _, key := range c.keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(key), formatFloat(*c.values[key]))
+	}
+}
+
+// DefaultBuckets suit durations in seconds, from a cached lookup to a slow render.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram counts observations into buckets, split by its labels.
+type Histogram struct {
+	series[histogramValue]
+	buckets []float64
+}
+
+type histogramValue struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram adds a histogram with the given upper bounds to r, nil
+// buckets take DefaultBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := &Histogram{newSeries[histogramValue](name, help, "histogram", labels), slices.Sorted(slices.Values(buckets))}
+	r.add(h)
+	return h
+}
+
+// Observe records v for the label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv := h.with(values, func() *histogramValue {
+		return &histogramValue{counts: make([]uint64, len(h.buckets))}
+	})
+	if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.count++
+	hv.sum += v
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+	for _, key := range h.keys {
+		hv := h.values[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hv.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(key, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(key, "le", "+Inf"), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(key), formatFloat(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(key), hv.count)
+	}
+}
+
+// Gauge reports whatever its function returns at scrape time.
+type Gauge struct {
+	name, help string
+	value      func() float64
+}
+
+// NewGauge adds a gauge read from value to r.
+func (r *Registry) NewGauge(name, help string, value func() float64) *Gauge {
+	g := &Gauge{name: name, help: help, value: value}
+	r.add(g)
+	return g
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatFloat(g.value()))
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
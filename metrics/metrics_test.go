@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := &Registry{}
+	commands := r.NewCounter("commands_total", "Commands handled.", "command", "outcome")
+	latency := r.NewHistogram("latency_seconds", "Request latency.", []float64{0.5, 0.1, 1}, "endpoint")
+	r.NewGauge("depth", "Queue depth.", func() float64 { return 3 })
+
+	commands.Inc("stats", "ok")
+	commands.Inc("stats", "ok")
+	commands.Inc("banner", "not_found")
+	latency.Observe(0.1, "player")
+	latency.Observe(0.3, "player")
+	latency.Observe(4, "player")
+
+	var out strings.Builder
+	if err := r.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP commands_total Commands handled.
+# TYPE commands_total counter
+commands_total{command="stats",outcome="ok"} 2
+commands_total{command="banner",outcome="not_found"} 1
+# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{endpoint="player",le="0.1"} 1
+latency_seconds_bucket{endpoint="player",le="0.5"} 2
+latency_seconds_bucket{endpoint="player",le="1"} 2
+latency_seconds_bucket{endpoint="player",le="+Inf"} 3
+latency_seconds_sum{endpoint="player"} 4.4
+latency_seconds_count{endpoint="player"} 3
+# HELP depth Queue depth.
+# TYPE depth gauge
+depth 3
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+	if v := commands.Value("stats", "ok"); v != 2 {
+		t.Errorf("Value = %v, want 2", v)
+	}
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic")
+		}
+	}()
+	(&Registry{}).NewCounter("c", "c", "a", "b").Inc("only one")
+}
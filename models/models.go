@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 type PlayerData struct {
 	Username         string               `json:"username"`
 	Online           bool                 `json:"online"`
@@ -64,6 +66,9 @@ type PvPStats struct {
 	Deaths int `json:"deaths"`
 }
 
+// Ranking holds leaderboard positions. The fields are the ones the cards
+// name, All has every position the api sent by its key, so leaderboards
+// added later can be looked up from data tables.
 type Ranking struct {
 	OrphionSrPlayers       int `json:"orphionSrPlayers"`
 	GrootslangSrPlayers    int `json:"grootslangSrPlayers"`
@@ -95,6 +100,38 @@ type Ranking struct {
 	AlchemismLevel         int `json:"alchemismLevel"`
 	WoodworkingLevel       int `json:"woodworkingLevel"`
 	ArmouringLevel         int `json:"armouringLevel"`
+
+	All map[string]int `json:"-"`
+}
+
+// UnmarshalJSON fills All alongside the fields. Positions that aren't whole
+// numbers, like a null for a leaderboard the player dropped off, are left
+// out rather than failing the whole player.
+func (r *Ranking) UnmarshalJSON(raw []byte) error {
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return err
+	}
+	all := make(map[string]int, len(entries))
+	for key, value := range entries {
+		var n int
+		if err := json.Unmarshal(value, &n); err == nil && string(value) != "null" {
+			all[key] = n
+		}
+	}
+
+	// the plain type has no UnmarshalJSON, so this doesn't recurse
+	type plain Ranking
+	valid, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	*r = Ranking{}
+	if err := json.Unmarshal(valid, (*plain)(r)); err != nil {
+		return err
+	}
+	r.All = all
+	return nil
 }
 
 type Character struct {
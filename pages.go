@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"wynn_bot/canvas"
+	"wynn_bot/models"
+	"wynn_bot/statscard"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// page is a card that goes deeper into one part of a player's stats.
+type page struct {
+	name  string // names the attachment and the render time metric
+	write func(ctx context.Context, w io.Writer, f canvas.Format, data models.PlayerData, opts statscard.Options) error
+}
+
+var (
+	raidsPage    = page{"raids", statscard.WriteRaidsCard}
+	dungeonsPage = page{"dungeons", statscard.WriteDungeonsCard}
+)
+
+// pageError is why a page can't be drawn for the options given, sent to the
+// user as is.
+type pageError string
+
+func (e pageError) Error() string { return string(e) }
+
+// professionsPage shows the character the command names, by default the
+// author's saved one when it's their own account, or else the best
+// character for each profession.
+func professionsPage(i *discordgo.InteractionCreate, opts optionMap) page {
+	query, saved := "", false
+	if opt, ok := opts["character"]; ok {
+		query = opt.StringValue()
+	} else if _, ok := opts["username"]; !ok {
+		query, saved = userPrefs.Get(interactionAuthor(i.Interaction).ID).Character, true
+	}
+
+	return page{"professions", func(ctx context.Context, w io.Writer, f canvas.Format, data models.PlayerData, cardOpts statscard.Options) error {
+		if query == "" {
+			return statscard.WriteProfessionsCard(ctx, w, f, data, nil, cardOpts)
+		}
+		char, ok := statscard.FindCharacter(data, query)
+		if !ok {
+			if saved {
+				// the preference may be from before a character was deleted
+				return statscard.WriteProfessionsCard(ctx, w, f, data, nil, cardOpts)
+			}
+			return pageError(fmt.Sprintf("%s has no character matching %s.", data.Username, query))
+		}
+		return statscard.WriteProfessionsCard(ctx, w, f, data, &char, cardOpts)
+	}}
+}
+
+func getPage(s responder, i *discordgo.InteractionCreate, opts optionMap, p page) {
+	waiting := "Generating " + p.name + " card, please wait..."
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: waiting,
+		},
+	})
+	if err != nil {
+		log.Printf("Could not respond to interaction: %s", err)
+		countCommand(i, outcomeFailed)
+		return
+	}
+
+	enqueueRender(s, i, waiting, func(ctx context.Context) {
+		drawPage(ctx, s, i, opts, p)
+	})
+}
+
+// drawPage fetches the player and sends the page's card, run from the render queue.
+func drawPage(ctx context.Context, s responder, i *discordgo.InteractionCreate, opts optionMap, p page) {
+	playerData, ok := lookupPlayer(ctx, s, i, opts)
+	if !ok {
+		return
+	}
+
+	start := time.Now()
+	var buffer bytes.Buffer
+	var reason pageError
+	err := p.write(ctx, &buffer, canvas.FormatPNG, playerData, cardOptions(i, opts))
+	if ctx.Err() != nil {
+		dropRender(ctx, s, i, fmt.Sprintf("the %s card for %s", p.name, playerData.Username))
+		return
+	} else if errors.As(err, &reason) {
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer(reason.Error()),
+		})
+		countCommand(i, outcomeInvalid)
+		return
+	} else if err != nil {
+		log.Printf("Failed to draw %s card: %s", p.name, err)
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer("Failed to generate " + p.name + " card."),
+		})
+		countCommand(i, outcomeFailed)
+		return
+	}
+	renderDuration.Observe(time.Since(start).Seconds(), p.name)
+	if ctx.Err() != nil {
+		dropRender(ctx, s, i, fmt.Sprintf("the %s card for %s", p.name, playerData.Username))
+		return
+	}
+
+	err = editWithRetries(s, i, func() *discordgo.WebhookEdit {
+		return &discordgo.WebhookEdit{
+			Content: stringPointer(""),
+			Files: []*discordgo.File{
+				{
+					Name:   p.name + ".png",
+					Reader: bytes.NewReader(buffer.Bytes()),
+				},
+			},
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to edit interaction response after retries: %s", err)
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer("Failed to edit interaction response after multiple attempts."),
+		})
+		countCommand(i, outcomeFailed)
+		return
+	}
+	countCommand(i, outcomeOK)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"wynn_bot/prefs"
+	"wynn_bot/statscard"
+	"wynn_bot/wynnapi"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const preferencesPath = "preferences.json"
+
+var userPrefs *prefs.Store
+
+var errNoUsername = errors.New("no username given and no linked account")
+
+// clearValue given to /link or a /preferences option removes what's saved.
+const clearValue = "none"
+
+// clearChoice lets options limited to choices be cleared too.
+var clearChoice = &discordgo.ApplicationCommandOptionChoice{Name: clearValue, Value: clearValue}
+
+func themeChoices() []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, name := range statscard.ThemeNames() {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  name,
+			Value: name,
+		})
+	}
+	return choices
+}
+
+func numberFormatChoices() []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, name := range prefs.NumberFormats {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  name,
+			Value: name,
+		})
+	}
+	return choices
+}
+
+// resolveUsername picks the username option, falling back to the caller's linked account.
+func resolveUsername(i *discordgo.Interaction, opts optionMap) (string, error) {
+	if opt, ok := opts["username"]; ok && opt.StringValue() != "" {
+		return opt.StringValue(), nil
+	}
+
+	if uuid := userPrefs.Get(interactionAuthor(i).ID).LinkedUUID; uuid != "" {
+		return uuid, nil
+	}
+	return "", errNoUsername
+}
+
+func respondEphemeral(s responder, i *discordgo.InteractionCreate, content string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Printf("Could not respond to interaction: %s", err)
+	}
+}
+
+func linkAccount(s responder, i *discordgo.InteractionCreate, opts optionMap) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Printf("Could not respond to interaction: %s", err)
+		countCommand(i, outcomeFailed)
+		return
+	}
+
+	username := opts["username"].StringValue()
+	if strings.EqualFold(username, clearValue) {
+		unlinkAccount(s, i)
+		return
+	}
+
+	ctx, cancel := interactionContext(i)
+	defer cancel()
+	player, err := getPlayer(ctx, username)
+	if errors.Is(err, wynnapi.ErrNotFound) {
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer(fmt.Sprintf("Player %s was not found.", username)),
+		})
+		countCommand(i, outcomeNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to look up player to link: %s", err)
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer("Failed to look up that player, try again later."),
+		})
+		countCommand(i, outcomeAPIError)
+		return
+	}
+
+	err = userPrefs.Update(interactionAuthor(i.Interaction).ID, func(p *prefs.Preferences) {
+		p.LinkedUUID = player.UUID
+		p.LinkedName = player.Username
+	})
+	content := fmt.Sprintf("Linked to %s. Commands without a username will now show this account.", player.Username)
+	if err != nil {
+		log.Printf("Failed to save linked account: %s", err)
+		content = "Failed to save the linked account."
+		countCommand(i, outcomeFailed)
+	} else {
+		countCommand(i, outcomeOK)
+	}
+
+	_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPointer(content),
+	})
+}
+
+// unlinkAccount forgets the author's linked account, answering the deferred
+// response linkAccount sent.
+func unlinkAccount(s responder, i *discordgo.InteractionCreate) {
+	err := userPrefs.Update(interactionAuthor(i.Interaction).ID, func(p *prefs.Preferences) {
+		p.LinkedUUID = ""
+		p.LinkedName = ""
+	})
+	content := "Unlinked. Commands will need a username again."
+	if err != nil {
+		log.Printf("Failed to remove linked account: %s", err)
+		content = "Failed to remove the linked account."
+		countCommand(i, outcomeFailed)
+	} else {
+		countCommand(i, outcomeOK)
+	}
+
+	_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPointer(content),
+	})
+}
+
+// setPreferences saves the options given, where "none" clears one and reset
+// clears everything, the linked account included, before they're applied.
+func setPreferences(s responder, i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionAuthor(i.Interaction)
+
+	set := func(field *string, name string) {
+		if opt, ok := opts[name]; ok {
+			*field = opt.StringValue()
+			if strings.EqualFold(*field, clearValue) {
+				*field = ""
+			}
+		}
+	}
+	err := userPrefs.Update(user.ID, func(p *prefs.Preferences) {
+		if opt, ok := opts["reset"]; ok && opt.BoolValue() {
+			*p = prefs.Preferences{}
+		}
+		set(&p.Theme, "theme")
+		set(&p.Character, "character")
+		set(&p.Timezone, "timezone")
+		set(&p.NumberFormat, "number_format")
+	})
+	if err != nil {
+		log.Printf("Failed to save preferences: %s", err)
+		respondEphemeral(s, i, fmt.Sprintf("Failed to save preferences: %s.", err))
+		countCommand(i, outcomeInvalid)
+		return
+	}
+
+	respondEphemeral(s, i, "Preferences saved.\n"+describePreferences(userPrefs.Get(user.ID)))
+	countCommand(i, outcomeOK)
+}
+
+func describePreferences(p prefs.Preferences) string {
+	orDefault := func(v string) string {
+		if v == "" {
+			return "default"
+		}
+		return v
+	}
+
+	linked := p.LinkedName
+	if linked == "" {
+		linked = "none"
+	}
+
+	lines := []string{
+		"linked account: " + linked,
+		"theme: " + orDefault(p.Theme),
+		"character: " + orDefault(p.Character),
+		"timezone: " + orDefault(p.Timezone),
+		"number format: " + orDefault(p.NumberFormat),
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,116 @@
+package prefs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Preferences are the per-user settings remembered between commands.
+type Preferences struct {
+	// linked wynncraft account, used when a command is given no username
+	LinkedUUID string `json:"linkedUuid,omitempty"`
+	LinkedName string `json:"linkedName,omitempty"`
+
+	Theme        string `json:"theme,omitempty"`
+	Character    string `json:"character,omitempty"` // character uuid, nickname or class
+	Timezone     string `json:"timezone,omitempty"`  // IANA name, e.g. Europe/Berlin
+	NumberFormat string `json:"numberFormat,omitempty"`
+}
+
+// NumberFormats are the accepted values for Preferences.NumberFormat.
+var NumberFormats = []string{"compact", "grouped", "exact"}
+
+// Validate checks the fields that can't be limited to a fixed set of choices.
+func (p Preferences) Validate() error {
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", p.Timezone)
+		}
+	}
+	if p.NumberFormat != "" && !slices.Contains(NumberFormats, p.NumberFormat) {
+		return fmt.Errorf("unknown number format %q", p.NumberFormat)
+	}
+	return nil
+}
+
+// Store keeps preferences keyed by Discord user ID and writes them through to a JSON file.
+type Store struct {
+	path  string
+	mu    sync.RWMutex
+	users map[string]Preferences
+}
+
+// Open loads the store at path, a missing file gives an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, users: make(map[string]Preferences)}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preferences: %v", err)
+	}
+
+	if err := json.Unmarshal(raw, &s.users); err != nil {
+		return nil, fmt.Errorf("failed to decode preferences: %v", err)
+	}
+	return s, nil
+}
+
+// Get returns the preferences for a user, the zero value if they have none saved.
+func (s *Store) Get(userID string) Preferences {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.users[userID]
+}
+
+// Update applies fn to a user's preferences and saves the store.
+// Nothing changes if the result doesn't validate, and a user left with
+// nothing set is dropped from the file.
+func (s *Store) Update(userID string, fn func(p *Preferences)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.users[userID]
+	fn(&p)
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p == (Preferences{}) {
+		delete(s.users, userID)
+	} else {
+		s.users[userID] = p
+	}
+
+	return s.save()
+}
+
+// save writes to a temporary file first so a crash never leaves half a file behind.
+func (s *Store) save() error {
+	raw, err := json.MarshalIndent(s.users, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode preferences: %v", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create preferences directory: %v", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write preferences: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace preferences: %v", err)
+	}
+	return nil
+}
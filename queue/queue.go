@@ -0,0 +1,139 @@
+// Package queue runs renders on a fixed number of workers, so a burst of
+// commands waits its turn instead of drawing every card at once.
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBusy is returned when a user already has as many jobs as they may.
+var ErrBusy = errors.New("too many renders in flight")
+
+// Pool is a first in, first out queue served by a set of workers.
+type Pool struct {
+	perUser int
+
+	mu       sync.Mutex
+	ready    *sync.Cond
+	idle     *sync.Cond // broadcast when inFlight empties
+	waiting  []*job
+	inFlight map[string]int // queued or running, by user
+}
+
+type job struct {
+	ctx       context.Context
+	user      string
+	run       func(ctx context.Context)
+	positions chan int      // latest queue position, closed once the job starts
+	reported  chan struct{} // closed when the last position has been handled
+}
+
+// New starts workers goroutines and lets each user have perUser jobs
+// queued or running at once.
+func New(workers, perUser int) *Pool {
+	p := &Pool{perUser: max(1, perUser), inFlight: map[string]int{}}
+	p.ready = sync.NewCond(&p.mu)
+	p.idle = sync.NewCond(&p.mu)
+	for range max(1, workers) {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues run for user and returns without waiting for it. progress,
+// if not nil, is told the job's place in line (1 is next) whenever it
+// changes, from its own goroutine and never after run starts. A job whose
+// ctx is done before a worker reaches it still runs, straight away and with
+// that ctx, so it can tell whoever is waiting that it gave up.
+func (p *Pool) Submit(ctx context.Context, user string, run func(ctx context.Context), progress func(position int)) error {
+	j := &job{ctx: ctx, user: user, run: run, positions: make(chan int, 1), reported: make(chan struct{})}
+
+	p.mu.Lock()
+	if p.inFlight[user] >= p.perUser {
+		p.mu.Unlock()
+		return ErrBusy
+	}
+	p.inFlight[user]++
+	p.waiting = append(p.waiting, j)
+	j.report(len(p.waiting))
+	p.mu.Unlock()
+	p.ready.Signal()
+
+	go func() {
+		defer close(j.reported)
+		for position := range j.positions {
+			if progress != nil {
+				progress(position)
+			}
+		}
+	}()
+	return nil
+}
+
+// Len is how many jobs are waiting for a worker.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.waiting)
+}
+
+// Drain waits until no job is queued or running, including any submitted
+// while it waits, and returns ctx's error if ctx ends first.
+func (p *Pool) Drain(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.idle.Broadcast()
+	})
+	defer stop()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.inFlight) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.idle.Wait()
+	}
+	return nil
+}
+
+// report replaces any position not yet delivered with the latest one.
+func (j *job) report(position int) {
+	select {
+	case <-j.positions:
+	default:
+	}
+	j.positions <- position
+}
+
+func (p *Pool) work() {
+	for {
+		p.mu.Lock()
+		for len(p.waiting) == 0 {
+			p.ready.Wait()
+		}
+		j := p.waiting[0]
+		p.waiting = p.waiting[1:]
+		close(j.positions)
+		for i, other := range p.waiting {
+			other.report(i + 1)
+		}
+		p.mu.Unlock()
+
+		// a slow progress update mustn't land on top of the result
+		<-j.reported
+		j.run(j.ctx)
+
+		p.mu.Lock()
+		if p.inFlight[j.user]--; p.inFlight[j.user] <= 0 {
+			delete(p.inFlight, j.user)
+		}
+		if len(p.inFlight) == 0 {
+			p.idle.Broadcast()
+		}
+		p.mu.Unlock()
+	}
+}
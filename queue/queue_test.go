@@ -0,0 +1,161 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blocker holds the only worker until released.
+func blocker(p *Pool) (release func()) {
+	started, done := make(chan struct{}), make(chan struct{})
+	p.Submit(context.Background(), "blocker", func(context.Context) {
+		close(started)
+		<-done
+	}, nil)
+	<-started
+	return func() { close(done) }
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestRunsInOrder(t *testing.T) {
+	p := New(1, 1)
+	release := blocker(p)
+
+	var mu sync.Mutex
+	var order []string
+	all := make(chan struct{})
+	users := []string{"a", "b", "c"}
+	for _, user := range users {
+		err := p.Submit(context.Background(), user, func(context.Context) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, user)
+			if len(order) == len(users) {
+				close(all)
+			}
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := p.Len(); n != 3 {
+		t.Errorf("Len() = %d, want 3", n)
+	}
+
+	release()
+	waitFor(t, all)
+	if order[0] != "a" || order[1] != "b" || order[2] != "c" {
+		t.Errorf("ran in order %v", order)
+	}
+}
+
+func TestPerUserLimit(t *testing.T) {
+	p := New(1, 1)
+	release := blocker(p)
+	defer release()
+
+	if err := p.Submit(context.Background(), "a", func(context.Context) {}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Submit(context.Background(), "a", func(context.Context) {}, nil); !errors.Is(err, ErrBusy) {
+		t.Errorf("second job for the same user got %v, want ErrBusy", err)
+	}
+	if err := p.Submit(context.Background(), "b", func(context.Context) {}, nil); err != nil {
+		t.Errorf("another user was turned away: %v", err)
+	}
+}
+
+func TestCancelledJobsRunWithTheirContext(t *testing.T) {
+	p := New(1, 1)
+	release := blocker(p)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var got error
+	p.Submit(ctx, "a", func(ctx context.Context) { got = ctx.Err() }, nil)
+	cancel()
+
+	done := make(chan struct{})
+	p.Submit(context.Background(), "b", func(context.Context) { close(done) }, nil)
+	release()
+	waitFor(t, done)
+
+	if got != context.Canceled {
+		t.Errorf("the cancelled job ran with %v, want its cancelled context", got)
+	}
+	// the finished job no longer counts against its user
+	if err := p.Submit(context.Background(), "a", func(context.Context) {}, nil); err != nil {
+		t.Errorf("user still busy after their job was dropped: %v", err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	p := New(1, 1)
+	release := blocker(p)
+
+	p.Submit(context.Background(), "a", func(context.Context) {}, nil)
+
+	var mu sync.Mutex
+	var positions []int
+	done := make(chan struct{})
+	p.Submit(context.Background(), "b", func(context.Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		positions = append(positions, -1) // marks the start
+		close(done)
+	}, func(position int) {
+		mu.Lock()
+		defer mu.Unlock()
+		positions = append(positions, position)
+	})
+
+	release()
+	waitFor(t, done)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// updates nobody read yet are replaced, so 2 may be skipped but 1 can't be
+	if len(positions) < 2 || positions[0] > 2 || positions[len(positions)-2] != 1 || positions[len(positions)-1] != -1 {
+		t.Errorf("positions %v, want 2 then 1 then the run", positions)
+	}
+	for i := 1; i < len(positions)-1; i++ {
+		if positions[i] >= positions[i-1] {
+			t.Errorf("positions %v went backwards", positions)
+		}
+	}
+}
+
+func TestDrain(t *testing.T) {
+	p := New(1, 1)
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("draining an idle pool: %v", err)
+	}
+
+	release := blocker(p)
+	ran := false
+	p.Submit(context.Background(), "a", func(context.Context) { ran = true }, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain with a job still running got %v, want the deadline", err)
+	}
+
+	release()
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !ran {
+		t.Error("Drain returned before the queued job ran")
+	}
+}
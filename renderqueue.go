@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"strconv"
+	"time"
+
+	"wynn_bot/queue"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// interaction tokens last 15 minutes, work still queued after that can't be sent
+const interactionLifetime = 15 * time.Minute
+
+// renderTimeout bounds each render, fetches included, well inside the
+// token's lifetime so a hung one frees its worker. Tests shorten it.
+var renderTimeout = 45 * time.Second
+
+// errRenderTimeout is the cause of a render context that hit renderTimeout.
+var errRenderTimeout = errors.New("render took too long")
+
+// rootCtx ends when the bot starts shutting down, taking every fetch and
+// render still in flight with it. main replaces it before commands arrive.
+var rootCtx = context.Background()
+
+// interactionContext ends when i's token expires or the bot shuts down,
+// whichever comes first.
+func interactionContext(i *discordgo.InteractionCreate) (context.Context, context.CancelFunc) {
+	created, err := discordgo.SnowflakeTimestamp(i.ID)
+	if err != nil {
+		created = time.Now()
+	}
+	return context.WithDeadline(rootCtx, created.Add(interactionLifetime))
+}
+
+// renders draws cards a few at a time, set up in main from RENDER_WORKERS
+// and RENDER_PER_USER.
+var renders *queue.Pool
+
+func newRenderQueue() *queue.Pool {
+	workers, perUser := runtime.NumCPU(), 1
+	if raw, err := loadEnv("RENDER_WORKERS", false); err == nil {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			workers = n
+		}
+	}
+	if raw, err := loadEnv("RENDER_PER_USER", false); err == nil {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			perUser = n
+		}
+	}
+	return queue.New(workers, perUser)
+}
+
+// enqueueRender runs render on the render queue after the interaction has
+// been answered with waiting, keeping that reply updated with how many
+// renders are ahead of it. render's context is the interaction's, see
+// interactionContext, cut short at renderTimeout once the render starts. If
+// it ends while the render is still queued, render isn't called and the
+// reply says why instead.
+func enqueueRender(s responder, i *discordgo.InteractionCreate, waiting string, render func(ctx context.Context)) {
+	ctx, cancel := interactionContext(i)
+	err := renders.Submit(ctx, interactionAuthor(i.Interaction).ID, func(ctx context.Context) {
+		defer cancel()
+		if ctx.Err() != nil {
+			content, outcome := "Your card waited too long in the queue, try again.", outcomeExpired
+			if rootCtx.Err() != nil {
+				content, outcome = restartingMessage, outcomeRestarting
+			}
+			log.Printf("Dropping a queued render: %s", ctx.Err())
+			countCommand(i, outcome)
+			_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: stringPointer(content),
+			})
+			return
+		}
+		ctx, cancelRender := context.WithTimeoutCause(ctx, renderTimeout, errRenderTimeout)
+		defer cancelRender()
+		render(ctx)
+	}, func(position int) {
+		content := waiting
+		if position > 1 {
+			content = fmt.Sprintf("%s %d ahead of you in the queue.", waiting, position-1)
+		}
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Printf("Failed to update queue position: %s", err)
+		}
+	})
+	if errors.Is(err, queue.ErrBusy) {
+		cancel()
+		countCommand(i, outcomeBusy)
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer("You already have a card being drawn, try again once it's done."),
+		})
+	} else if err != nil {
+		cancel()
+		log.Printf("Failed to queue render: %s", err)
+		countCommand(i, outcomeFailed)
+	}
+}
+
+// dropRender gives up on what a render was drawing once its context has
+// ended. A render that ran out of renderTimeout can still say so, an
+// expired token or a shutdown can't be answered any more.
+func dropRender(ctx context.Context, s responder, i *discordgo.InteractionCreate, what string) {
+	log.Printf("Dropping %s: %s", what, context.Cause(ctx))
+	countCommand(i, outcomeExpired)
+	if errors.Is(context.Cause(ctx), errRenderTimeout) {
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPointer("Drawing your card took too long, try again."),
+		})
+	}
+}
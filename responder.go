@@ -0,0 +1,32 @@
+package main
+
+import (
+	"time"
+
+	"wynn_bot/avatar"
+	"wynn_bot/statscard"
+	"wynn_bot/wynnapi"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// responder is the part of *discordgo.Session the command handlers answer
+// through, so they can be tested without discord.
+type responder interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// what the handlers look things up with, tests replace them
+var (
+	getPlayer = wynnapi.GetPlayer
+	getGuild  = wynnapi.GetGuild
+	findGuild = wynnapi.FindGuild
+
+	renderStatsCard = statscard.RenderStatsCard
+
+	avatars avatar.Provider // avatar.Default if nil
+)
+
+// how long editWithRetries waits between attempts
+var editRetryDelay = time.Second
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeResponder records what the handlers send, in place of discord.
+type fakeResponder struct {
+	mu          sync.Mutex
+	responses   []*discordgo.InteractionResponse
+	edits       []sentEdit // every attempt, failed or not
+	failUploads int        // how many of the next edits with files fail
+	failRespond bool       // the initial response fails
+}
+
+// sentEdit is an edit with its files read, since a reader only reads once.
+type sentEdit struct {
+	content string
+	embeds  []*discordgo.MessageEmbed
+	files   map[string][]byte
+	failed  bool
+}
+
+func (f *fakeResponder) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failRespond {
+		return errors.New("unknown interaction")
+	}
+	f.responses = append(f.responses, resp)
+	return nil
+}
+
+func (f *fakeResponder) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	edit := sentEdit{files: map[string][]byte{}}
+	if newresp.Content != nil {
+		edit.content = *newresp.Content
+	}
+	if newresp.Embeds != nil {
+		edit.embeds = *newresp.Embeds
+	}
+	for _, file := range newresp.Files {
+		raw, err := io.ReadAll(file.Reader)
+		if err != nil {
+			return nil, err
+		}
+		edit.files[file.Name] = raw
+	}
+	if len(edit.files) > 0 && f.failUploads > 0 {
+		f.failUploads--
+		edit.failed = true
+		f.edits = append(f.edits, edit)
+		return nil, errors.New("upload failed")
+	}
+	f.edits = append(f.edits, edit)
+	return &discordgo.Message{Content: edit.content}, nil
+}
+
+// sent is every edit that went through, in order.
+func (f *fakeResponder) sent() []sentEdit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var sent []sentEdit
+	for _, edit := range f.edits {
+		if !edit.failed {
+			sent = append(sent, edit)
+		}
+	}
+	return sent
+}
+
+// last is the edit the user ends up seeing.
+func (f *fakeResponder) last() sentEdit {
+	sent := f.sent()
+	if len(sent) == 0 {
+		return sentEdit{}
+	}
+	return sent[len(sent)-1]
+}
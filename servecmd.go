@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"wynn_bot/server"
+)
+
+// where serve listens unless -addr is given, the bot only serves cards
+// when HTTP_ADDR is set in secrets.env
+const defaultServeAddr = ":8080"
+
+// how long requests already being drawn get to finish on shutdown
+const serverShutdownTimeout = 10 * time.Second
+
+// how long a client gets to send its headers, so slow ones can't hold
+// connections open
+const serverReadHeaderTimeout = 10 * time.Second
+
+// runServe is the `wynn_bot serve` subcommand, it serves the cards over HTTP
+// without connecting to discord.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", defaultServeAddr, "address to listen on")
+	concurrency := fs.Int("concurrency", runtime.NumCPU(), "cards drawn at once")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("serving cards on %s", *addr)
+	if err := listenAndServe(ctx, *addr, server.New(*concurrency).Handler()); err != nil {
+		log.Printf("server stopped: %s", err)
+		return 1
+	}
+	return 0
+}
+
+// startServer serves cards next to the bot, sharing its api cache and rate limiter.
+func startServer(ctx context.Context, addr string) {
+	go func() {
+		log.Printf("serving cards on %s", addr)
+		if err := listenAndServe(ctx, addr, server.New(runtime.NumCPU()).Handler()); err != nil {
+			log.Printf("card server stopped: %s", err)
+		}
+	}()
+}
+
+// listenAndServe serves handler until ctx ends. Requests see a context that
+// outlives ctx until they've had serverShutdownTimeout to finish, then the
+// lookups and renders behind them stop too.
+func listenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return serveUntil(ctx, ln, handler)
+}
+
+func serveUntil(ctx context.Context, ln net.Listener, handler http.Handler) error {
+	// ending ctx starts the shutdown, so requests can't have it as a parent or
+	// they'd be cancelled before the grace period starts
+	base, cancelBase := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelBase()
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+		BaseContext:       func(net.Listener) context.Context { return base },
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		err := srv.Shutdown(shutdownCtx)
+		cancelBase()
+		done <- err
+	}()
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-done
+}
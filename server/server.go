@@ -0,0 +1,338 @@
+// Package server serves the cards the bot draws over HTTP, for sites that
+// want to embed them.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wynn_bot/avatar"
+	"wynn_bot/banner"
+	"wynn_bot/canvas"
+	"wynn_bot/chartings"
+	"wynn_bot/humanize"
+	"wynn_bot/models"
+	"wynn_bot/statscard"
+	"wynn_bot/timefmt"
+	"wynn_bot/wynnapi"
+)
+
+// Server renders cards on request. The lookups default to wynnapi, so a
+// server running next to the bot shares its response cache and rate limiter.
+type Server struct {
+	Players func(ctx context.Context, name string) (models.PlayerData, error)
+	Guilds  func(ctx context.Context, query string) (models.GuildData, error)
+	Avatars avatar.Provider // avatar.Default if nil
+
+	// MaxAge is how long clients and proxies may reuse a card.
+	MaxAge time.Duration
+
+	slots chan struct{}
+}
+
+// New returns a Server drawing at most concurrency cards at once.
+func New(concurrency int) *Server {
+	return &Server{
+		Players: wynnapi.GetPlayer,
+		Guilds:  wynnapi.FindGuild,
+		MaxAge:  5 * time.Minute,
+		slots:   make(chan struct{}, max(1, concurrency)),
+	}
+}
+
+// Handler routes:
+//
+//	GET /card/player/{name}.png?theme=&tz=&locale=&numbers=&format=
+//	GET /card/player/{name}.svg?theme=&tz=&locale=&numbers=
+//	GET /card/player/{name}.gif?theme=&tz=&locale=&numbers=
+//	GET /card/guild/{name or prefix}.png?scale=&format=
+//	GET /chart/points.png?x=1,2,3&y=4,5,6&title=&xlabel=&ylabel=&width=&height=&format=
+//	GET /chart/points.svg?x=1,2,3&y=4,5,6&title=&xlabel=&ylabel=&width=&height=
+//
+// format=png8 on a .png reduces it to a 256 colour palette.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /card/player/{file}", s.playerCard)
+	mux.HandleFunc("GET /card/guild/{file}", s.guildCard)
+	mux.HandleFunc("GET /chart/{file}", s.pointsChart)
+	return mux
+}
+
+// fileName splits a path segment into the name and the format its extension
+// and ?format= ask for, false if it's not a .png, .svg or .gif.
+func fileName(r *http.Request) (string, canvas.Format, bool) {
+	file := r.PathValue("file")
+	for _, f := range []canvas.Format{canvas.FormatSVG, canvas.FormatGIF} {
+		if name, ok := strings.CutSuffix(file, f.Ext()); ok {
+			return name, f, name != ""
+		}
+	}
+	name, ok := strings.CutSuffix(file, ".png")
+	if !ok || name == "" {
+		return "", "", false
+	}
+	if r.URL.Query().Get("format") == string(canvas.FormatPNG8) {
+		return name, canvas.FormatPNG8, true
+	}
+	return name, canvas.FormatPNG, true
+}
+
+func (s *Server) playerCard(w http.ResponseWriter, r *http.Request) {
+	name, format, ok := fileName(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+
+	player, err := s.Players(r.Context(), name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var guild *models.GuildData
+	if player.Guild != nil {
+		if g, err := s.Guilds(r.Context(), player.Guild.Name); err != nil {
+			log.Printf("Failed to fetch guild, drawing the card without it: %s", err)
+		} else {
+			guild = &g
+		}
+	}
+
+	opts, err := cardOptions(query.Get("theme"), query.Get("tz"), query.Get("locale"), query.Get("numbers"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Chart = statscard.ChartOptions{Seed: statscard.SeedFor(player.UUID)}
+	opts.Avatars = s.Avatars
+
+	// "last seen" moves on without the data changing, so the tag does too
+	version := struct {
+		Player models.PlayerData
+		Guild  *models.GuildData
+		Hour   time.Time
+	}{player, guild, time.Now().Truncate(time.Hour)}
+
+	s.serve(w, r, version, format, func() ([]byte, error) {
+		var buf bytes.Buffer
+		if err := statscard.WriteStatsCard(r.Context(), &buf, format, player, guild, opts); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+// cardOptions mirrors the bot's preferences, empty values take the defaults.
+func cardOptions(theme, tz, locale, numbers string) (statscard.Options, error) {
+	var opts statscard.Options
+	var err error
+	if opts.Theme, err = statscard.GetTheme(theme); err != nil {
+		return opts, err
+	}
+	if opts.Time, err = timefmt.New(tz, locale); err != nil {
+		return opts, err
+	}
+	opts.Numbers = humanize.ForLocale(locale)
+	if numbers != "" {
+		if opts.Numbers.Mode, err = humanize.ParseMode(numbers); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func (s *Server) guildCard(w http.ResponseWriter, r *http.Request) {
+	// the poster is a bitmap all the way down, so there's no svg of it
+	name, format, ok := fileName(r)
+	if !ok || format == canvas.FormatSVG || format == canvas.FormatGIF {
+		http.NotFound(w, r)
+		return
+	}
+
+	scale := 2
+	if raw := r.URL.Query().Get("scale"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 4 {
+			http.Error(w, "scale must be 1 to 4", http.StatusBadRequest)
+			return
+		}
+		scale = n
+	}
+
+	guild, err := s.Guilds(r.Context(), name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.serve(w, r, guild, format, func() ([]byte, error) {
+		img, err := banner.Default.Poster(guild, scale, banner.Colors)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := canvas.EncodeImage(&buf, img, format); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+// the largest chart the server draws, per side and in points
+const maxChartSize, maxChartPoints = 2000, 500
+
+func (s *Server) pointsChart(w http.ResponseWriter, r *http.Request) {
+	name, format, ok := fileName(r)
+	if !ok || name != "points" || format == canvas.FormatGIF {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	data := &chartings.ChartData{
+		Title:  query.Get("title"),
+		XLabel: query.Get("xlabel"),
+		YLabel: query.Get("ylabel"),
+		Width:  600,
+		Height: 400,
+	}
+
+	var err error
+	if data.X, err = parseFloats(query.Get("x")); err != nil {
+		http.Error(w, "x: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if data.Y, err = parseFloats(query.Get("y")); err != nil {
+		http.Error(w, "y: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(data.X) != len(data.Y) || len(data.X) == 0 || len(data.X) > maxChartPoints {
+		http.Error(w, fmt.Sprintf("x and y need the same number of points, 1 to %d", maxChartPoints), http.StatusBadRequest)
+		return
+	}
+	for _, side := range []struct {
+		name string
+		v    *int
+	}{{"width", &data.Width}, {"height", &data.Height}} {
+		raw := query.Get(side.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 100 || n > maxChartSize {
+			http.Error(w, fmt.Sprintf("%s must be 100 to %d", side.name, maxChartSize), http.StatusBadRequest)
+			return
+		}
+		*side.v = n
+	}
+
+	s.serve(w, r, data, format, func() ([]byte, error) {
+		var buf bytes.Buffer
+		if err := chartings.Encode(r.Context(), &buf, format, data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+func parseFloats(raw string) ([]float64, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var values []float64
+	for _, part := range strings.Split(raw, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", part)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// serve answers with what render draws in format, or 304 if the client's
+// copy has the tag version hashes to, which saves drawing anything.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, version any, format canvas.Format, render func() ([]byte, error)) {
+	etag, err := etagFor(r, version)
+	if err != nil {
+		log.Printf("Failed to tag %s: %s", r.URL.Path, err)
+		http.Error(w, "failed to render", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.MaxAge.Seconds())))
+	if matches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-r.Context().Done():
+		http.Error(w, "timed out waiting to render", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := render()
+	<-s.slots
+	if r.Context().Err() != nil {
+		// the client went away or the server is shutting down, nobody to answer
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to render %s: %s", r.URL.Path, err)
+		http.Error(w, "failed to render", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+}
+
+// matches reports whether an If-None-Match header names etag. The header is
+// a comma separated list, and weak tags match too since a 304 only needs
+// them to be the same card.
+func matches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func etagFor(r *http.Request, version any) (string, error) {
+	raw, err := json.Marshal(version)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery + "\n"))
+	h.Write(raw)
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, wynnapi.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, wynnapi.ErrRequest), errors.Is(err, wynnapi.ErrDecode):
+		log.Printf("Upstream error: %s", err)
+		http.Error(w, "the wynncraft api is unavailable", http.StatusBadGateway)
+	default:
+		log.Printf("Lookup failed: %s", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"image"
+	"image/gif"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"wynn_bot/avatar"
+	"wynn_bot/models"
+	"wynn_bot/wynnapi"
+)
+
+// TestMain runs from the repo root so the renderers find their assets.
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func readFixture[T any](t *testing.T, name string) T {
+	t.Helper()
+	raw, err := os.ReadFile("statscard/testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func testServer(t *testing.T) *httptest.Server {
+	player := readFixture[models.PlayerData](t, "player.json")
+	guild := readFixture[models.GuildData](t, "guild.json")
+
+	s := New(2)
+	s.Players = func(_ context.Context, name string) (models.PlayerData, error) {
+		if name != player.Username {
+			return models.PlayerData{}, wynnapi.ErrNotFound
+		}
+		return player, nil
+	}
+	s.Guilds = func(_ context.Context, query string) (models.GuildData, error) {
+		if query != guild.Name && query != guild.Prefix {
+			return models.GuildData{}, wynnapi.ErrNotFound
+		}
+		return guild, nil
+	}
+	s.Avatars = avatar.ProviderFunc(func(context.Context, avatar.Player) (image.Image, error) {
+		return avatar.Silhouette(), nil
+	})
+
+	server := httptest.NewServer(s.Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, url, etag string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestCards(t *testing.T) {
+	server := testServer(t)
+
+	tests := []struct {
+		path string
+		size image.Point
+	}{
+		{"/card/player/starfaiien.png?theme=light&tz=Europe/Berlin&locale=de", image.Pt(562, 952)},
+		{"/card/guild/SEQ.png?scale=1", image.Pt(208, 444)},
+		{"/chart/points.png?x=1,2,3&y=3,1,2&title=test&width=300&height=200", image.Pt(300, 200)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp := get(t, server.URL+tt.path, "")
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %s", resp.Status)
+			}
+			if got := resp.Header.Get("Content-Type"); got != "image/png" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			if resp.Header.Get("Cache-Control") == "" {
+				t.Error("no Cache-Control header")
+			}
+			img, err := png.Decode(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Bounds().Size() != tt.size {
+				t.Errorf("image is %v, want %v", img.Bounds().Size(), tt.size)
+			}
+
+			etag := resp.Header.Get("ETag")
+			if etag == "" {
+				t.Fatal("no ETag header")
+			}
+			if again := get(t, server.URL+tt.path, etag); again.StatusCode != http.StatusNotModified {
+				t.Errorf("revalidating got %s, want 304", again.Status)
+			}
+		})
+	}
+}
+
+func TestFormats(t *testing.T) {
+	server := testServer(t)
+
+	full := get(t, server.URL+"/card/player/starfaiien.png", "")
+	compact := get(t, server.URL+"/card/player/starfaiien.png?format=png8", "")
+	fullBody, _ := io.ReadAll(full.Body)
+	compactBody, _ := io.ReadAll(compact.Body)
+	img, err := png.Decode(bytes.NewReader(compactBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := img.(*image.Paletted); !ok {
+		t.Errorf("format=png8 gave a %T, want a paletted image", img)
+	}
+	if len(compactBody) >= len(fullBody) {
+		t.Errorf("the png8 card is %d bytes, no smaller than the full %d", len(compactBody), len(fullBody))
+	}
+	if full.Header.Get("ETag") == compact.Header.Get("ETag") {
+		t.Error("both formats share an ETag")
+	}
+
+	animated := get(t, server.URL+"/card/player/starfaiien.gif", "")
+	if got := animated.Header.Get("Content-Type"); got != "image/gif" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if anim, err := gif.DecodeAll(animated.Body); err != nil || len(anim.Image) < 2 {
+		t.Errorf("the .gif isn't animated: %v", err)
+	}
+
+	for _, path := range []string{"/card/player/starfaiien.svg?theme=light", "/chart/points.svg?x=1,2,3&y=3,1,2&title=test"} {
+		resp := get(t, server.URL+path, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %s", path, resp.Status)
+		}
+		if got := resp.Header.Get("Content-Type"); got != "image/svg+xml" {
+			t.Errorf("%s: Content-Type = %q", path, got)
+		}
+		if err := xml.NewDecoder(resp.Body).Decode(new(struct{})); err != nil {
+			t.Errorf("%s: not well formed: %v", path, err)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server := testServer(t)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/card/player/nobody.png", http.StatusNotFound},
+		{"/card/player/starfaiien", http.StatusNotFound},
+		{"/card/player/starfaiien.png?theme=neon", http.StatusBadRequest},
+		{"/card/player/starfaiien.png?tz=Mars/Olympus", http.StatusBadRequest},
+		{"/card/guild/SEQ.png?scale=9", http.StatusBadRequest},
+		{"/chart/points.png?x=1,2&y=1", http.StatusBadRequest},
+		{"/chart/points.png?x=a&y=1", http.StatusBadRequest},
+		{"/card/guild/SEQ.svg", http.StatusNotFound},
+		{"/chart/lines.png?x=1&y=1", http.StatusNotFound},
+		{"/chart/points.gif?x=1&y=1", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if resp := get(t, server.URL+tt.path, ""); resp.StatusCode != tt.status {
+			t.Errorf("%s: status %s, want %d", tt.path, resp.Status, tt.status)
+		}
+	}
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	const etag = `"abc"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{``, false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{`"xyz",W/"abc"`, true},
+		{`*`, true},
+		{`"xyz"`, false},
+		{`"abc`, false},
+	}
+	for _, tt := range tests {
+		if got := matches(tt.header, etag); got != tt.want {
+			t.Errorf("matches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
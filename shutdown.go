@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"wynn_bot/queue"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// how long in-flight commands get to finish once a shutdown starts, unless
+// SHUTDOWN_GRACE is set
+const defaultShutdownGrace = 30 * time.Second
+
+// how long cancelled commands get to wind down, still replying on the
+// session, before it's closed under them
+const cancelledWait = 5 * time.Second
+
+// where the api cache is kept between restarts
+const apiCachePath = "api_cache.json"
+
+const restartingMessage = "The bot is restarting, try again in a minute."
+
+// session is the part of *discordgo.Session shutting down needs, so tests
+// can stand in for discord.
+type session interface {
+	responder
+	Close() error
+}
+
+// lifecycle lets the bot finish what it started before it goes down. Once
+// shutdown begins new commands are turned away, and the ones in flight get
+// a grace period before their contexts are cancelled.
+type lifecycle struct {
+	session session
+	renders *queue.Pool        // waited for after the handlers, nil for none
+	cancel  context.CancelFunc // ends rootCtx
+	flush   []func() error     // run once in-flight work is done, before the session closes
+
+	mu       sync.Mutex
+	closing  bool
+	handlers sync.WaitGroup
+}
+
+// handle runs handler for i, or tells the user the bot is restarting if a
+// shutdown has begun.
+func (l *lifecycle) handle(i *discordgo.InteractionCreate, handler func()) {
+	l.mu.Lock()
+	if l.closing {
+		l.mu.Unlock()
+		err := l.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: restartingMessage,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			log.Printf("Could not respond to interaction: %s", err)
+		}
+		countCommand(i, outcomeRestarting)
+		return
+	}
+	l.handlers.Add(1)
+	l.mu.Unlock()
+
+	defer l.handlers.Done()
+	handler()
+}
+
+// shutdown stops taking commands, waits up to grace for the ones in flight,
+// cancels whatever is left and gives it a moment to stop, then flushes and
+// closes the session.
+func (l *lifecycle) shutdown(grace time.Duration) error {
+	l.mu.Lock()
+	l.closing = true
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	err := l.wait(ctx)
+	l.cancel()
+	if err != nil {
+		log.Printf("Cancelling the commands still running after %s", grace)
+		// they may still be editing replies and filling the cache
+		ctx, cancel := context.WithTimeout(context.Background(), cancelledWait)
+		defer cancel()
+		if err := l.wait(ctx); err != nil {
+			log.Printf("Closing with commands still running %s after cancelling them", cancelledWait)
+		}
+	}
+
+	var errs []error
+	for _, flush := range l.flush {
+		if err := flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := l.session.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close session: %v", err))
+	}
+	return errors.Join(errs...)
+}
+
+// wait returns once the handlers and the renders they queued are done, or
+// with ctx's error.
+func (l *lifecycle) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		l.handlers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// handlers queue their renders before returning, so none are missed here
+	if l.renders != nil {
+		return l.renders.Drain(ctx)
+	}
+	return nil
+}
+
+func shutdownGrace() time.Duration {
+	if raw, err := loadEnv("SHUTDOWN_GRACE", false); err == nil {
+		if d, err := time.ParseDuration(raw); err == nil && d >= 0 {
+			return d
+		}
+		log.Printf("Ignoring SHUTDOWN_GRACE %q, using %s", raw, defaultShutdownGrace)
+	}
+	return defaultShutdownGrace
+}
+
+// logToFile copies the log to path as well as stderr, the returned flush
+// syncs and closes it.
+func logToFile(path string) (flush func() error, err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %v", err)
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, f))
+	return func() error {
+		log.SetOutput(os.Stderr)
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to flush log file: %v", err)
+		}
+		return f.Close()
+	}, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wynn_bot/queue"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeSession records what shutdown tells discord.
+type fakeSession struct {
+	fakeResponder
+	events []string // responds, flushes and closes in order, guarded by mu
+}
+
+func (f *fakeSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	f.events = append(f.events, "respond")
+	f.mu.Unlock()
+	return f.fakeResponder.InteractionRespond(interaction, resp, options...)
+}
+
+func (f *fakeSession) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, "close")
+	return nil
+}
+
+func (f *fakeSession) log() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.events...)
+}
+
+func interaction(id string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{ID: id}}
+}
+
+func newTestLifecycle(s *fakeSession) (*lifecycle, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &lifecycle{session: s, renders: queue.New(1, 1), cancel: cancel}
+	l.flush = []func() error{func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.events = append(s.events, "flush")
+		return nil
+	}}
+	return l, ctx
+}
+
+// startShutdown runs shutdown in the background and waits until it stops
+// taking commands.
+func startShutdown(t *testing.T, l *lifecycle, grace time.Duration) <-chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- l.shutdown(grace) }()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		l.mu.Lock()
+		closing := l.closing
+		l.mu.Unlock()
+		if closing {
+			return done
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("shutdown never started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestShutdownWaitsForInFlightCommands(t *testing.T) {
+	s := &fakeSession{}
+	l, ctx := newTestLifecycle(s)
+
+	// a command that has queued a render, both still running when the signal comes
+	started, release := make(chan struct{}), make(chan struct{})
+	rendered := false
+	go l.handle(interaction("1"), func() {
+		l.renders.Submit(ctx, "a", func(ctx context.Context) {
+			<-release
+			rendered = ctx.Err() == nil
+		}, nil)
+		close(started)
+	})
+	<-started
+
+	done := startShutdown(t, l, time.Minute)
+
+	ran := false
+	l.handle(interaction("2"), func() { ran = true })
+	if ran {
+		t.Error("a command ran after shutdown began")
+	}
+	if got := s.log(); len(got) != 1 || got[0] != "respond" || s.responses[0].Data.Content != restartingMessage {
+		t.Errorf("new command got %v, want the restarting reply and the session still open", got)
+	}
+
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown didn't finish")
+	}
+
+	if !rendered {
+		t.Error("the in-flight render was cancelled instead of finishing")
+	}
+	if got := s.log(); len(got) != 3 || got[1] != "flush" || got[2] != "close" {
+		t.Errorf("events %v, want flush then close", got)
+	}
+}
+
+func TestShutdownCancelsAfterGrace(t *testing.T) {
+	s := &fakeSession{}
+	l, ctx := newTestLifecycle(s)
+
+	// a command that would never finish on its own, and replies once cancelled
+	started := make(chan struct{})
+	go l.handle(interaction("1"), func() {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.events = append(s.events, "reply")
+	})
+	<-started
+
+	done := startShutdown(t, l, 20*time.Millisecond)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown waited past its grace period")
+	}
+
+	if ctx.Err() == nil {
+		t.Error("the root context wasn't cancelled")
+	}
+	if got := s.log(); len(got) != 3 || got[0] != "reply" || got[2] != "close" {
+		t.Errorf("events %v, want the cancelled command's reply, then flush and close", got)
+	}
+}
+
+// discordgo drops handlers whose type isn't one of its event funcs with only
+// a log line, so a wrong signature would leave every command unanswered.
+func TestInteractionHandlerType(t *testing.T) {
+	var handler any = (&lifecycle{}).onInteraction
+	if _, ok := handler.(func(*discordgo.Session, *discordgo.InteractionCreate)); !ok {
+		t.Fatalf("the interaction handler is a %T, which discordgo won't call", handler)
+	}
+}
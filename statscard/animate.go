@@ -0,0 +1,130 @@
+package statscard
+
+import (
+	"context"
+	"image"
+	"io"
+	"math"
+	"time"
+
+	"wynn_bot/canvas"
+	"wynn_bot/models"
+)
+
+// Animation is how an animated card moves, for guild announcements. The
+// stats count up from nothing and the online indicator pulses, on a loop.
+type Animation struct {
+	// LevelledUp maps the classes that just levelled up to their level
+	// before, and only their rings sweep, on from there. Nil sweeps every
+	// ring from nothing, for a milestone.
+	LevelledUp map[string]int
+	// Frames is how many frames the loop draws, more is smoother and
+	// slower to draw. animationFrames if zero.
+	Frames int
+	// MaxBytes bounds the GIF, frames are dropped until it fits.
+	// DefaultMaxGIFBytes if zero.
+	MaxBytes int
+}
+
+// ClassLevels is the highest level of each class the player has, the
+// levels the card's rings show.
+func ClassLevels(data models.PlayerData) map[string]int {
+	levels := map[string]int{}
+	for _, char := range data.Characters {
+		if char.Level > levels[char.Type] {
+			levels[char.Type] = char.Level
+		}
+	}
+	return levels
+}
+
+// LevelledUp compares ClassLevels from before with now's and returns what
+// Animation.LevelledUp wants, nil if no class went up.
+func LevelledUp(before, now map[string]int) map[string]int {
+	var up map[string]int
+	for class, level := range now {
+		if was := before[class]; level > was {
+			if up == nil {
+				up = map[string]int{}
+			}
+			up[class] = was
+		}
+	}
+	return up
+}
+
+// DefaultMaxGIFBytes leaves room under discord's 10MB upload limit.
+const DefaultMaxGIFBytes = 8 << 20
+
+// the animation counts up over the first part of the loop, then rests on
+// the finished card
+const (
+	animationFrames = 30
+	frameDelay      = 60 * time.Millisecond
+	finishedHold    = 2500 * time.Millisecond
+	countingShare   = 0.7
+	pulsesPerLoop   = 2
+)
+
+// frame is one moment of an animated card.
+type frame struct {
+	progress   float64        // 0 to 1, how far the stats have counted up
+	pulse      float64        // 0 to 1, how bright the online indicator is
+	levelledUp map[string]int // see Animation, nil sweeps every ring
+}
+
+// still is the static card, everything counted and at full brightness.
+var still = frame{progress: 1, pulse: 1}
+
+// frameAt is frame i of n, counting up with an ease out so the numbers
+// settle rather than stop.
+func frameAt(i, n int, anim Animation) frame {
+	t := 1.0
+	if counting := countingShare * float64(n-1); counting > 0 {
+		t = min(1, float64(i)/counting)
+	}
+	return frame{
+		progress:   1 - math.Pow(1-t, 3),
+		pulse:      0.5 + 0.5*math.Cos(2*math.Pi*pulsesPerLoop*float64(i)/float64(n)),
+		levelledUp: anim.LevelledUp,
+	}
+}
+
+func (f frame) count(v int) int {
+	return int(math.Round(float64(v) * f.progress))
+}
+
+// classLevel is the level a class's ring shows, its new level once done.
+func (f frame) classLevel(class string, level int) int {
+	if f.levelledUp == nil {
+		return f.count(level)
+	}
+	before, ok := f.levelledUp[class]
+	if !ok {
+		return level
+	}
+	return before + int(math.Round(float64(level-before)*f.progress))
+}
+
+// animateStatsCard draws every frame of the card with the static layout and
+// writes them as a GIF.
+func animateStatsCard(ctx context.Context, w io.Writer, data models.PlayerData, guild *models.GuildData, opts Options, avatarImg image.Image) error {
+	var anim Animation
+	if opts.Animation != nil {
+		anim = *opts.Animation
+	}
+	if anim.Frames == 0 {
+		anim.Frames = animationFrames
+	}
+	if anim.MaxBytes == 0 {
+		anim.MaxBytes = DefaultMaxGIFBytes
+	}
+
+	gifOpts := canvas.GIFOptions{Frames: anim.Frames, Delay: frameDelay, Hold: finishedHold, MaxBytes: anim.MaxBytes}
+	return canvas.EncodeGIF(w, width, height, gifOpts, func(card canvas.Canvas, i, n int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return drawStatsCard(card, data, guild, opts, avatarImg, frameAt(i, n, anim))
+	})
+}
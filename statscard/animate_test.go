@@ -0,0 +1,107 @@
+package statscard
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/draw"
+	"image/gif"
+	"testing"
+	"time"
+
+	"wynn_bot/canvas"
+	"wynn_bot/internal/golden"
+	"wynn_bot/models"
+)
+
+func animate(t *testing.T, data models.PlayerData, guild *models.GuildData, opts Options) ([]byte, *gif.GIF) {
+	t.Helper()
+	opts.Avatars, opts.Time = offline, fixedTime
+	var buf bytes.Buffer
+	if err := WriteStatsCard(context.Background(), &buf, canvas.FormatGIF, data, guild, opts); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), anim
+}
+
+// The animation ends on the static card, give or take its palette.
+func TestAnimatedCardEndsOnStill(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	guild := readFixture[models.GuildData](t, "guild.json")
+	player.Online = true
+
+	_, anim := animate(t, player, &guild, Options{})
+	if len(anim.Image) < 10 {
+		t.Fatalf("only %d frames", len(anim.Image))
+	}
+	if anim.LoopCount != 0 {
+		t.Errorf("loops %d times, want forever", anim.LoopCount)
+	}
+
+	last := image.NewRGBA(image.Rect(0, 0, width, height))
+	for _, frame := range anim.Image {
+		draw.Draw(last, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	}
+	still, err := RenderStatsCard(context.Background(), player, &guild, Options{Avatars: offline, Time: fixedTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, n := golden.Diff(still, last, golden.Default); n > width*height/100 {
+		t.Errorf("the last frame differs from the card in %d pixels", n)
+	}
+}
+
+func TestAnimatedCardFitsMaxBytes(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+
+	const bound = 150_000
+	raw, anim := animate(t, player, nil, Options{Animation: &Animation{MaxBytes: bound}})
+	if len(raw) > bound {
+		t.Errorf("the gif is %d bytes, over the %d allowed", len(raw), bound)
+	}
+	// dropping frames keeps the length of the loop
+	total := 0
+	for _, d := range anim.Delay {
+		total += d
+	}
+	if want := int((animationFrames*frameDelay + finishedHold) / (10 * time.Millisecond)); total < want-5 || total > want+10 {
+		t.Errorf("loops in %d0ms, want about %d0ms", total, want)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	first, last := frameAt(0, 30, Animation{}), frameAt(29, 30, Animation{})
+	if first.count(3976) != 0 || last.count(3976) != 3976 {
+		t.Errorf("counted %d to %d, want 0 to 3976", first.count(3976), last.count(3976))
+	}
+	if first.classLevel("MAGE", 105) != 0 || last.classLevel("MAGE", 105) != 105 {
+		t.Error("a milestone doesn't sweep the rings from nothing")
+	}
+
+	levelledUp := Animation{LevelledUp: map[string]int{"MAGE": 100}}
+	first, last = frameAt(0, 30, levelledUp), frameAt(29, 30, levelledUp)
+	if first.classLevel("MAGE", 105) != 100 || last.classLevel("MAGE", 105) != 105 {
+		t.Error("the levelled up ring doesn't sweep on from its old level")
+	}
+	if first.classLevel("SHAMAN", 80) != 80 {
+		t.Error("a ring that didn't level up moved")
+	}
+	if still.classLevel("MAGE", 105) != 105 || still.count(7) != 7 {
+		t.Error("the still card isn't finished")
+	}
+}
+
+func TestLevelledUp(t *testing.T) {
+	before := map[string]int{"MAGE": 100, "SHAMAN": 80}
+	if got := LevelledUp(before, before); got != nil {
+		t.Errorf("nothing changed but got %v", got)
+	}
+	got := LevelledUp(before, map[string]int{"MAGE": 102, "SHAMAN": 80, "ARCHER": 5})
+	if len(got) != 2 || got["MAGE"] != 100 || got["ARCHER"] != 0 {
+		t.Errorf("got %v, want MAGE from 100 and a new ARCHER from 0", got)
+	}
+}
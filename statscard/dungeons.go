@@ -0,0 +1,262 @@
+package statscard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fogleman/gg"
+
+	"wynn_bot/canvas"
+	"wynn_bot/chartings"
+	"wynn_bot/models"
+)
+
+// DungeonTablePath is where the dungeons card reads which dungeons there
+// are, like RaidTablePath. It's also how the card knows which ones a player
+// has never done, since the api only lists what they have.
+const DungeonTablePath = "statscard/data/dungeons.json"
+
+// corruptedPrefix is how the api names a dungeon's corrupted version.
+const corruptedPrefix = "Corrupted "
+
+// Dungeon is a dungeon and which of its versions can be played.
+type Dungeon struct {
+	Name      string `json:"name"`  // the regular version's, as the api lists it
+	Short     string `json:"short"` // under its bar on the chart
+	Regular   bool   `json:"regular"`
+	Corrupted bool   `json:"corrupted"`
+}
+
+// DungeonTable is the dungeons in the order they're drawn.
+type DungeonTable struct {
+	Dungeons []Dungeon `json:"dungeons"`
+}
+
+// LoadDungeonTable reads a dungeon table from a JSON file.
+func LoadDungeonTable(path string) (DungeonTable, error) {
+	var table DungeonTable
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return table, fmt.Errorf("failed to read dungeon table: %v", err)
+	}
+	if err := json.Unmarshal(raw, &table); err != nil {
+		return table, fmt.Errorf("failed to decode dungeon table: %v", err)
+	}
+	return table, nil
+}
+
+// dungeonTable is the table at DungeonTablePath, read once like raidTable.
+var dungeonTable = sync.OnceValues(func() (DungeonTable, error) {
+	return LoadDungeonTable(DungeonTablePath)
+})
+
+// dungeonRow is one dungeon's line, both versions together.
+type dungeonRow struct {
+	dungeon            Dungeon
+	regular, corrupted int
+	characters         []characterCount // both versions, most first
+}
+
+// never is what the row flags as not done yet, empty if nothing.
+func (row dungeonRow) never() string {
+	regular := row.dungeon.Regular && row.regular == 0
+	corrupted := row.dungeon.Corrupted && row.corrupted == 0
+	switch {
+	case regular && corrupted:
+		return "never completed"
+	case regular && row.dungeon.Corrupted:
+		return "regular never completed"
+	case regular:
+		return "never completed"
+	case corrupted && row.dungeon.Regular:
+		return "corrupted never completed"
+	case corrupted:
+		return "never completed"
+	}
+	return ""
+}
+
+// dungeonRows is the table's dungeons, then any others the player has done
+// by name, with each corrupted version counted with its regular one.
+func dungeonRows(data models.PlayerData, table DungeonTable) []dungeonRow {
+	dungeons := slices.Clone(table.Dungeons)
+	known := map[string]bool{}
+	for _, dungeon := range dungeons {
+		known[dungeon.Name] = true
+	}
+	unknown := map[string]*Dungeon{}
+	for name := range data.GlobalData.Dungeons.List {
+		base, corrupted := strings.CutPrefix(name, corruptedPrefix)
+		if known[base] {
+			continue
+		}
+		d, ok := unknown[base]
+		if !ok {
+			d = &Dungeon{Name: base, Short: strings.ToUpper(initials(base))}
+			unknown[base] = d
+		}
+		d.Regular = d.Regular || !corrupted
+		d.Corrupted = d.Corrupted || corrupted
+	}
+	names := make([]string, 0, len(unknown))
+	for name := range unknown {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		dungeons = append(dungeons, *unknown[name])
+	}
+
+	rows := make([]dungeonRow, len(dungeons))
+	for i, dungeon := range dungeons {
+		corrupted := corruptedPrefix + dungeon.Name
+		rows[i] = dungeonRow{
+			dungeon:   dungeon,
+			regular:   data.GlobalData.Dungeons.List[dungeon.Name],
+			corrupted: data.GlobalData.Dungeons.List[corrupted],
+		}
+		rows[i].characters, _ = characterCounts(data, func(char models.Character) int {
+			return char.Dungeons.List[dungeon.Name] + char.Dungeons.List[corrupted]
+		})
+	}
+	return rows
+}
+
+// the dungeons card is a chart of every dungeon over a line for each
+const (
+	dungeonChartHeight = 250
+	dungeonRowHeight   = 56
+)
+
+func dungeonsHeight(rows int) int {
+	return headerHeight + pagePadding + dungeonChartHeight + pagePadding + rows*dungeonRowHeight + 2*pagePadding
+}
+
+// RenderDungeonsCard draws the player's dungeon completions, a line per
+// dungeon in the table at DungeonTablePath. Drawing stops with ctx's error
+// once it ends.
+func RenderDungeonsCard(ctx context.Context, data models.PlayerData, opts Options) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	table, err := dungeonTable()
+	if err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+	rows := dungeonRows(data, table)
+	card := gg.NewContext(width, dungeonsHeight(len(rows)))
+	if err := drawDungeonsCard(ctx, card, data, rows, opts); err != nil {
+		return nil, err
+	}
+	return card.Image(), nil
+}
+
+// WriteDungeonsCard draws the dungeons card into w as f, stopping like
+// RenderDungeonsCard.
+func WriteDungeonsCard(ctx context.Context, w io.Writer, f canvas.Format, data models.PlayerData, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	table, err := dungeonTable()
+	if err != nil {
+		return err
+	}
+	opts = opts.withDefaults()
+	rows := dungeonRows(data, table)
+	return canvas.Encode(w, f, width, dungeonsHeight(len(rows)), func(card canvas.Canvas) error {
+		return drawDungeonsCard(ctx, card, data, rows, opts)
+	})
+}
+
+func drawDungeonsCard(ctx context.Context, card canvas.Canvas, data models.PlayerData, rows []dungeonRow, opts Options) error {
+	theme, num := opts.Theme, opts.Numbers
+
+	subtitle := num.Int(data.GlobalData.Dungeons.Total) + " dungeons completed"
+	never := 0
+	for _, row := range rows {
+		if row.never() != "" {
+			never++
+		}
+	}
+	if never > 0 {
+		subtitle += fmt.Sprintf(" · %d not done yet", never)
+	}
+	if err := drawPage(card, data, theme, dungeonsHeight(len(rows)), subtitle); err != nil {
+		return err
+	}
+
+	// the chart, regular versions with the corrupted ones stacked on top
+	y := float64(headerHeight + pagePadding)
+	drawPanel(card, theme, y, dungeonChartHeight)
+	chart := &chartings.ChartData{
+		Kind:       chartings.Bars,
+		Title:      "completions",
+		Width:      width - 20,
+		Height:     dungeonChartHeight,
+		Colors:     []string{theme.DungeonColors["regular"], theme.DungeonColors["corrupted"]},
+		Background: color.Transparent,
+		Foreground: theme.Text,
+	}
+	corrupted := make([]float64, len(rows))
+	for i, row := range rows {
+		chart.Y = append(chart.Y, float64(row.regular))
+		corrupted[i] = float64(row.corrupted)
+		chart.XLegends = append(chart.XLegends, row.dungeon.Short)
+	}
+	chart.Stacked = [][]float64{corrupted}
+	chartings.Draw(canvas.Offset(card, 10, y, float64(chart.Width), float64(chart.Height)), chart)
+
+	// a line per dungeon
+	y += dungeonChartHeight + pagePadding
+	drawPanel(card, theme, y, float64(len(rows)*dungeonRowHeight+pagePadding))
+	const left, right = 30.0, width - 30.0
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rowY := y + pagePadding/2 + float64(i*dungeonRowHeight)
+
+		if err := card.LoadFontFace(theme.Fonts.Body, 17); err != nil {
+			return err
+		}
+		card.SetColor(theme.Text)
+		card.DrawStringAnchored(row.dungeon.Name, left, rowY+18, 0, 0.5)
+
+		// the counts, corrupted rightmost
+		x := right
+		if row.dungeon.Corrupted {
+			card.SetHexColor(theme.DungeonColors["corrupted"])
+			text := num.Int(row.corrupted) + " corrupted"
+			card.DrawStringAnchored(text, x, rowY+18, 1, 0.5)
+			w, _ := card.MeasureString(text)
+			x -= w + 16
+		}
+		if row.dungeon.Regular {
+			card.SetHexColor(theme.DungeonColors["regular"])
+			card.DrawStringAnchored(num.Int(row.regular), x, rowY+18, 1, 0.5)
+		}
+
+		// who did them, and what's left
+		if err := card.LoadFontFace(theme.Fonts.Body, 13); err != nil {
+			return err
+		}
+		legendWidth := right - left
+		if flag := row.never(); flag != "" {
+			card.SetHexColor(theme.DungeonColors["never"])
+			card.DrawStringAnchored(flag, right, rowY+40, 1, 0.5)
+			w, _ := card.MeasureString(flag)
+			legendWidth -= w + 16
+		}
+		drawLegend(card, theme, characterLegend(row.characters, theme, opts), left, rowY+40, legendWidth)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package statscard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"wynn_bot/canvas"
+	"wynn_bot/models"
+)
+
+func TestDungeonsCard(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+
+	for _, theme := range []string{"dark", "light"} {
+		t.Run(theme, func(t *testing.T) {
+			th, err := GetTheme(theme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := RenderDungeonsCard(context.Background(), player, Options{Theme: th})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, "dungeons_"+theme, img)
+		})
+	}
+}
+
+// The chart is drawn onto the card, so an SVG keeps it as vectors.
+func TestDungeonsCardSVG(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+
+	var buf bytes.Buffer
+	if err := WriteDungeonsCard(context.Background(), &buf, canvas.FormatSVG, player, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), ">completions<") {
+		t.Error("the chart's title isn't text in the svg")
+	}
+}
+
+func TestDungeonsCardStopsWithContext(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RenderDungeonsCard(ctx, player, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want the context's error", err)
+	}
+}
+
+func TestDungeonRows(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	// a dungeon added after the table was written, only done corrupted
+	player.GlobalData.Dungeons.List["Corrupted Frozen Depths"] = 2
+
+	table, err := LoadDungeonTable(DungeonTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := dungeonRows(player, table)
+	if len(rows) != len(table.Dungeons)+1 {
+		t.Fatalf("got %d rows, want the table's %d and the new one", len(rows), len(table.Dungeons))
+	}
+
+	sewers := rows[0]
+	if sewers.regular != 20 || sewers.corrupted != 5 {
+		t.Errorf("sewers are %d and %d corrupted, want 20 and 5", sewers.regular, sewers.corrupted)
+	}
+	var counts []int
+	for _, c := range sewers.characters {
+		counts = append(counts, c.count)
+	}
+	if want := []int{15, 8, 2}; !slices.Equal(counts, want) {
+		t.Errorf("sewers characters are %v, want %v", counts, want)
+	}
+
+	added := rows[len(rows)-1]
+	if want := (Dungeon{Name: "Frozen Depths", Short: "FD", Corrupted: true}); added.dungeon != want {
+		t.Errorf("the new dungeon is %+v, want %+v", added.dungeon, want)
+	}
+
+	never := map[string]string{}
+	for _, row := range rows {
+		never[row.dungeon.Name] = row.never()
+	}
+	for name, want := range map[string]string{
+		"Decrepit Sewers":   "",
+		"Infested Pit":      "corrupted never completed",
+		"Lost Sanctuary":    "never completed",
+		"Timelost Sanctum":  "",
+		"Eldritch Outlook":  "never completed",
+		"Undergrowth Ruins": "never completed",
+		"Frozen Depths":     "",
+	} {
+		if never[name] != want {
+			t.Errorf("%s is flagged %q, want %q", name, never[name], want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package statscard
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"image/png"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+
+	"wynn_bot/humanize"
+	"wynn_bot/mctext"
+	"wynn_bot/models"
+	"wynn_bot/timefmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// BadgeName is the attachment the embed's thumbnail points at, send
+// RankBadgePNG under it.
+const BadgeName = "rank.png"
+
+// discord drops an embed field longer than this
+const maxFieldLength = 1024
+
+// Embed lays the card's stats out as a discord embed, for readers the image
+// doesn't suit and for when drawing it fails. Only Theme and Numbers of opts
+// are used, timestamps are left for discord to show in each reader's zone.
+func Embed(data models.PlayerData, guild *models.GuildData, opts Options) *discordgo.MessageEmbed {
+	opts = opts.withDefaults()
+	theme, num := opts.Theme, opts.Numbers
+
+	colour := theme.Username
+	if data.LegacyRankColour != nil {
+		colour = hexColor(data.LegacyRankColour.Main)
+	}
+
+	title := data.Username
+	if data.Veteran != nil && *data.Veteran {
+		title += " · VETERAN"
+	}
+
+	var description []string
+	if data.Nickname != nil && *data.Nickname != "" {
+		description = append(description, "*~"+plainText(*data.Nickname)+"*")
+	}
+	if t, err := timefmt.Parse(data.FirstJoin); err == nil {
+		description = append(description, "first joined "+timefmt.Discord(t, timefmt.LongDate))
+	}
+	if data.Online && data.Server != nil {
+		description = append(description, "currently online on world "+*data.Server)
+	} else if t, err := timefmt.Parse(data.LastJoin); err == nil {
+		lastSeen := "last seen " + timefmt.Discord(t, timefmt.RelativeTime)
+		if data.Server != nil {
+			lastSeen += " on world " + *data.Server
+		}
+		description = append(description, lastSeen)
+	}
+
+	stat := func(name string, value string) *discordgo.MessageEmbedField {
+		return &discordgo.MessageEmbedField{Name: name, Value: value, Inline: true}
+	}
+	global := data.GlobalData
+	fields := []*discordgo.MessageEmbedField{
+		stat("playtime", num.Format(math.Round(data.Playtime))+" hr"),
+		stat("total levels", num.Int(global.TotalLevel)),
+		stat("kills", num.Int(global.KilledMobs)),
+		stat("chests", num.Int(global.ChestsFound)),
+		stat("dungeons", num.Int(global.Dungeons.Total)),
+		stat("quests", num.Int(global.CompletedQuests)),
+		stat("wars", num.Int(global.Wars)),
+		{Name: "raids", Value: raidSplit(global.Raids, num)},
+	}
+	if classes := classLevels(data.Characters, num); classes != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "classes", Value: classes})
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{Name: "leaderboards", Value: strings.Join([]string{
+		"completion #" + num.Int(data.Ranking.GlobalPlayerContent),
+		"professions #" + num.Int(data.Ranking.ProfessionsGlobalLevel),
+		"wars won #" + num.Int(data.Ranking.WarsCompletion),
+	}, " · ")})
+	if guild != nil && data.Guild != nil {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "guild", Value: guildLine(data, guild, num)})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       title,
+		URL:         "https://wynncraft.com/stats/player/" + url.PathEscape(data.Username),
+		Description: strings.Join(description, "\n"),
+		Color:       int(colour.R)<<16 | int(colour.G)<<8 | int(colour.B),
+		Thumbnail:   &discordgo.MessageEmbedThumbnail{URL: "attachment://" + BadgeName},
+		Fields:      fields,
+	}
+}
+
+// RankBadgePNG is the badge drawn on the card, for the embed's thumbnail.
+func RankBadgePNG(data models.PlayerData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, ResolveRankBadge(data)); err != nil {
+		return nil, fmt.Errorf("failed to encode rank badge: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// raidSplit lists each raid in the table with its completions and share,
+// like the card's donut. Without a table it's just the total.
+func raidSplit(raids models.RaidSummary, num humanize.Options) string {
+	table, _ := raidTable()
+	counts := make([]int, len(table.Raids))
+	for i, raid := range table.Raids {
+		counts[i] = raids.List[raid.Name]
+	}
+	shares := percentages(counts)
+
+	lines := []string{"**" + num.Int(raids.Total) + "** total"}
+	for i, raid := range table.Raids {
+		lines = append(lines, fmt.Sprintf("%s %s (%d%%)", raid.Short, num.Int(counts[i]), shares[i]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// classLevels lists the characters from highest level down, as many as fit
+// in a field.
+func classLevels(characters map[string]models.Character, num humanize.Options) string {
+	chars := make([]models.Character, 0, len(characters))
+	for _, char := range characters {
+		chars = append(chars, char)
+	}
+	slices.SortStableFunc(chars, func(a, b models.Character) int {
+		return cmp.Or(cmp.Compare(b.Level, a.Level), cmp.Compare(a.Type, b.Type), cmp.Compare(b.TotalLevel, a.TotalLevel))
+	})
+
+	var b strings.Builder
+	for _, char := range chars {
+		line := strings.ToLower(char.Type) + " " + num.Int(char.Level)
+		if char.Nickname != nil && *char.Nickname != "" {
+			line += " (" + plainText(*char.Nickname) + ")"
+		}
+		if b.Len()+len(line)+1 > maxFieldLength {
+			break
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func guildLine(data models.PlayerData, guild *models.GuildData, num humanize.Options) string {
+	member := guildMember(guild, data.Username, data.Guild.Rank)
+	line := fmt.Sprintf("%s of **%s** [%s], lv %s", strings.ToLower(data.Guild.Rank), guild.Name, guild.Prefix, num.Int(guild.Level))
+	if t, err := timefmt.Parse(member.Joined); err == nil {
+		line += "\nsince " + timefmt.Discord(t, timefmt.LongDate)
+	}
+	line += "\n" + num.Int(member.Contributed) + " xp contributed"
+	if member.ContributionRank != nil {
+		line += " (#" + num.Int(*member.ContributionRank) + ")"
+	}
+	return line
+}
+
+// plainText drops minecraft formatting codes, which discord would show as is.
+func plainText(s string) string {
+	var b strings.Builder
+	for _, segment := range mctext.Parse(s) {
+		b.WriteString(segment.Text)
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package statscard
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"wynn_bot/models"
+)
+
+func TestEmbed(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	guild := readFixture[models.GuildData](t, "guild.json")
+
+	embed := Embed(player, &guild, Options{})
+	if embed.Title != "starfaiien · VETERAN" {
+		t.Errorf("title %q", embed.Title)
+	}
+	if embed.Thumbnail == nil || embed.Thumbnail.URL != "attachment://"+BadgeName {
+		t.Errorf("thumbnail %+v", embed.Thumbnail)
+	}
+	if strings.Contains(embed.Description, "§") {
+		t.Errorf("formatting codes left in %q", embed.Description)
+	}
+
+	fields := map[string]string{}
+	for _, field := range embed.Fields {
+		if len(field.Value) > maxFieldLength {
+			t.Errorf("field %s is %d long", field.Name, len(field.Value))
+		}
+		fields[field.Name] = field.Value
+	}
+	for name, want := range map[string]string{
+		"raids":   "tcc 185 (32%)",
+		"classes": "archer 106\nmage 105\nshaman 80\nwarrior 34",
+		"guild":   "captain of **Sequoia** [SEQ]",
+	} {
+		if !strings.Contains(fields[name], want) {
+			t.Errorf("%s field %q doesn't have %q", name, fields[name], want)
+		}
+	}
+
+	if embed := Embed(player, nil, Options{}); strings.Contains(embed.Fields[len(embed.Fields)-1].Name, "guild") {
+		t.Error("a guild field without the guild")
+	}
+}
+
+func TestRankBadgePNG(t *testing.T) {
+	raw, err := RankBadgePNG(readFixture[models.PlayerData](t, "player.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(raw)); err != nil {
+		t.Error(err)
+	}
+}
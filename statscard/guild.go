@@ -0,0 +1,147 @@
+package statscard
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"log"
+
+	"github.com/fogleman/gg"
+
+	"wynn_bot/banner"
+	"wynn_bot/canvas"
+	"wynn_bot/models"
+)
+
+// the guild card is the framed banner beside a panel of the guild's stats,
+// under a header like the pages'
+const (
+	guildBannerWidth, guildBannerHeight = banner.Width * 5 / 4, banner.Height * 5 / 4
+	guildLabelHeight                    = 40
+	guildHeight                         = headerHeight + pagePadding + guildBannerHeight + guildLabelHeight + pagePadding
+)
+
+// RenderGuildCard draws a guild's stats beside its banner in the frame of its
+// tier. Drawing stops with ctx's error once it ends.
+func RenderGuildCard(ctx context.Context, guild models.GuildData, opts Options) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	card := gg.NewContext(width, guildHeight)
+	if err := drawGuildCard(ctx, card, guild, opts.withDefaults()); err != nil {
+		return nil, err
+	}
+	return card.Image(), nil
+}
+
+// WriteGuildCard draws the guild card into w as f, stopping like
+// RenderGuildCard.
+func WriteGuildCard(ctx context.Context, w io.Writer, f canvas.Format, guild models.GuildData, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	opts = opts.withDefaults()
+	return canvas.Encode(w, f, width, guildHeight, func(card canvas.Canvas) error {
+		return drawGuildCard(ctx, card, guild, opts)
+	})
+}
+
+func drawGuildCard(ctx context.Context, card canvas.Canvas, guild models.GuildData, opts Options) error {
+	theme, num := opts.Theme, opts.Numbers
+
+	card.SetColor(theme.Background)
+	card.Clear()
+	if theme.BackgroundImage != "" {
+		background, err := LoadImage(theme.BackgroundImage)
+		if err != nil {
+			return fmt.Errorf("failed to load background: %v", err)
+		}
+		card.DrawImage(coverImage(background, width, guildHeight), 0, 0)
+	}
+
+	// the xp bar takes the tier's colour, like the frame
+	accent := theme.Username
+	if frame, ok := banner.FrameFor(guild.Banner.Tier); ok {
+		accent = frame.Inner
+	}
+
+	// header
+	card.SetColor(theme.Box)
+	card.DrawRectangle(0, 0, headerWidth, headerHeight)
+	card.Fill()
+	if err := card.LoadFontFace(theme.Fonts.Display, 42); err != nil {
+		return err
+	}
+	card.SetColor(theme.Username)
+	card.DrawStringAnchored(guild.Name, 20, 30, 0, 0.4)
+	if err := card.LoadFontFace(theme.Fonts.Body, 18); err != nil {
+		return err
+	}
+	card.SetColor(theme.Text)
+	subtitle := "[" + guild.Prefix + "]"
+	if founded, err := opts.Time.Date(guild.Created); err == nil {
+		subtitle += " · founded " + founded
+	} else {
+		log.Printf("skipping guild creation date: %v", err)
+	}
+	card.DrawStringAnchored(subtitle, 20, 78, 0, 0.5)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// banner, framed by tier, with the tier and structure under it
+	const bannerX, bannerY = 20, headerHeight + pagePadding
+	card.DrawImage(banner.Default.RenderFramed(guild.Banner, guildBannerWidth, guildBannerHeight, banner.Colors), bannerX, bannerY)
+	if label := banner.Label(guild.Banner); label != "" {
+		if err := card.LoadFontFace(theme.Fonts.Body, 14); err != nil {
+			return err
+		}
+		card.SetColor(theme.Text)
+		card.DrawStringAnchored(label, bannerX+guildBannerWidth/2, bannerY+guildBannerHeight+guildLabelHeight/2, 0.5, 0.5)
+	}
+
+	// stats
+	const left = bannerX + guildBannerWidth + 20
+	const panelX, panelW = left, width - 10 - left
+	const x0, x1 = panelX + 20, panelX + panelW - 20
+	card.SetColor(theme.Panel)
+	card.DrawRoundedRectangle(panelX, bannerY, panelW, guildBannerHeight, pagePanelRadius)
+	card.Fill()
+
+	if err := card.LoadFontFace(theme.Fonts.Body, 16); err != nil {
+		return err
+	}
+	card.SetColor(theme.Text)
+	card.DrawStringAnchored("level", x0, bannerY+30, 0, 0.5)
+	if err := card.LoadFontFace(theme.Fonts.Display, 28); err != nil {
+		return err
+	}
+	card.DrawStringAnchored(num.Int(guild.Level), x1, bannerY+30, 1, 0.5)
+	drawBar(card, theme, x0, bannerY+52, x1-x0, 10, []slice{{guild.XPPercent, hexString(accent)}}, 100)
+	if err := card.LoadFontFace(theme.Fonts.Body, 14); err != nil {
+		return err
+	}
+	card.SetColor(theme.Text)
+	card.DrawStringAnchored(fmt.Sprintf("%d%% to level %s", guild.XPPercent, num.Int(guild.Level+1)), x0, bannerY+78, 0, 0.5)
+
+	rows := []struct{ label, value string }{
+		{"members", num.Int(guild.Members.Total)},
+		{"online", num.Int(guild.Online)},
+		{"territories", num.Int(guild.Territories)},
+		{"wars", num.Int(guild.Wars)},
+	}
+	for i, row := range rows {
+		y := float64(bannerY + 150 + i*60)
+		if err := card.LoadFontFace(theme.Fonts.Body, 16); err != nil {
+			return err
+		}
+		card.DrawStringAnchored(row.label, x0, y, 0, 0.5)
+		if err := card.LoadFontFace(theme.Fonts.Display, 24); err != nil {
+			return err
+		}
+		card.DrawStringAnchored(row.value, x1, y, 1, 0.5)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package statscard
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"wynn_bot/models"
+)
+
+func TestGuildCard(t *testing.T) {
+	guild := readFixture[models.GuildData](t, "guild.json")
+	untiered := guild
+	untiered.Banner.Tier = 0
+	untiered.Banner.Structure = ""
+
+	for name, tt := range map[string]struct {
+		guild models.GuildData
+		theme string
+	}{
+		"guild_dark":     {guild, "dark"},
+		"guild_light":    {guild, "light"},
+		"guild_untiered": {untiered, "dark"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			th, err := GetTheme(tt.theme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := RenderGuildCard(context.Background(), tt.guild, Options{Theme: th, Time: fixedTime})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, name, img)
+		})
+	}
+}
+
+func TestGuildCardStopsWithContext(t *testing.T) {
+	guild := readFixture[models.GuildData](t, "guild.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RenderGuildCard(ctx, guild, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want the context's error", err)
+	}
+}
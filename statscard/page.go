@@ -0,0 +1,216 @@
+package statscard
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+
+	"github.com/fogleman/gg"
+
+	"wynn_bot/canvas"
+	"wynn_bot/humanize"
+	"wynn_bot/mctext"
+	"wynn_bot/models"
+	"wynn_bot/timefmt"
+)
+
+// the cards that go deeper into one part of a player's stats are pages:
+// the stats card's width and header over a column of panels
+const (
+	pagePadding     = 15
+	pagePanelRadius = 15
+)
+
+// withDefaults fills in what opts leaves zero.
+func (opts Options) withDefaults() Options {
+	if opts.Theme.Name == "" {
+		opts.Theme = Themes[DefaultThemeName]
+	}
+	if opts.Time == nil {
+		opts.Time = timefmt.Default()
+	}
+	if opts.Numbers == (humanize.Options{}) {
+		opts.Numbers = humanize.Default
+	}
+	return opts
+}
+
+// usernameStyle draws the name in the player's rank colours, or the theme's.
+func usernameStyle(data models.PlayerData, theme Theme) mctext.Style {
+	style := mctext.Style{From: theme.Username, Shadow: true}
+	if data.LegacyRankColour != nil {
+		style.From = hexColor(data.LegacyRankColour.Main)
+		style.To = hexColor(data.LegacyRankColour.Sub)
+	}
+	return style
+}
+
+// drawPage paints a page h tall with the player's badge and name in the
+// header and subtitle under them.
+func drawPage(card canvas.Canvas, data models.PlayerData, theme Theme, h int, subtitle string) error {
+	card.SetColor(theme.Background)
+	card.Clear()
+	if theme.BackgroundImage != "" {
+		background, err := LoadImage(theme.BackgroundImage)
+		if err != nil {
+			return fmt.Errorf("failed to load background: %v", err)
+		}
+		card.DrawImage(coverImage(background, width, h), 0, 0)
+	}
+
+	card.SetColor(theme.Box)
+	card.DrawRectangle(0, 0, headerWidth, headerHeight)
+	card.Fill()
+
+	rankImg := ResolveRankBadge(data)
+	card.DrawImageAnchored(rankImg, 15, 30, 0, 0.5)
+	if err := card.LoadFontFace(theme.Fonts.Display, 42); err != nil {
+		return err
+	}
+	mctext.Draw(card, []mctext.Segment{{Text: data.Username}}, usernameStyle(data, theme), float64(rankImg.Bounds().Max.X)+30, 30, 0, 0.4)
+
+	if err := card.LoadFontFace(theme.Fonts.Body, 18); err != nil {
+		return err
+	}
+	card.SetColor(theme.Text)
+	card.DrawStringAnchored(subtitle, 20, 78, 0, 0.5)
+	return nil
+}
+
+// coverImage scales img to cover w by h, cropping what overflows, since a
+// page can be taller than the backgrounds drawn for the stats card.
+func coverImage(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	scale := max(float64(w)/float64(b.Dx()), float64(h)/float64(b.Dy()))
+	if scale <= 1 {
+		return img
+	}
+	dc := gg.NewContext(w, h)
+	dc.Translate(float64(w)/2, 0)
+	dc.Scale(scale, scale)
+	dc.DrawImageAnchored(img, 0, 0, 0.5, 0)
+	return dc.Image()
+}
+
+// drawPanel is the rounded box each section of a page sits in.
+func drawPanel(card canvas.Canvas, theme Theme, y, h float64) {
+	card.SetColor(theme.Panel)
+	card.DrawRoundedRectangle(10, y, width-20, h, pagePanelRadius)
+	card.Fill()
+}
+
+// drawBar fills a w wide bar with parts in proportion to total, the rest
+// in the theme's inactive colour.
+func drawBar(card canvas.Canvas, theme Theme, x, y, w, h float64, parts []slice, total int) {
+	card.SetHexColor(theme.Inactive)
+	card.DrawRectangle(x, y, w, h)
+	card.Fill()
+	if total <= 0 {
+		return
+	}
+	for _, part := range parts {
+		if part.value <= 0 {
+			continue
+		}
+		partW := w * float64(min(part.value, total)) / float64(total)
+		card.SetHexColor(part.color)
+		card.DrawRectangle(x, y, partW, h)
+		card.Fill()
+		x += partW
+		total -= min(part.value, total)
+		w -= partW
+		if total == 0 {
+			return
+		}
+	}
+}
+
+// legendItem is a coloured word in a legend line.
+type legendItem struct {
+	text  string
+	color string
+}
+
+// drawLegend writes the items along a line in their colours, ending with
+// "+n more" in text colour where the line runs out.
+func drawLegend(card canvas.Canvas, theme Theme, items []legendItem, x, y, maxWidth float64) {
+	const gap = 14
+	right := x + maxWidth
+	for i, item := range items {
+		w, _ := card.MeasureString(item.text)
+		if rest := len(items) - i - 1; rest > 0 {
+			// leave room to say how many didn't fit
+			more, _ := card.MeasureString(fmt.Sprintf("+%d more", rest))
+			if x+w+gap+more > right && i > 0 {
+				card.SetColor(theme.Text)
+				card.DrawStringAnchored(fmt.Sprintf("+%d more", len(items)-i), x, y, 0, 0.5)
+				return
+			}
+		}
+		card.SetHexColor(item.color)
+		card.DrawStringAnchored(item.text, x, y, 0, 0.5)
+		x += w + gap
+	}
+}
+
+// characterName is how a page labels a character: the class, and the
+// nickname when it has one.
+func characterName(char models.Character) string {
+	name := strings.ToLower(char.Type)
+	if char.Nickname != nil && *char.Nickname != "" {
+		name += " (" + plainText(*char.Nickname) + ")"
+	}
+	return name
+}
+
+// characterCount is how many of something one character has done.
+type characterCount struct {
+	character models.Character
+	count     int
+}
+
+// characterCounts is the characters that have done any of what count
+// counts, most first, and their total.
+func characterCounts(data models.PlayerData, count func(models.Character) int) ([]characterCount, int) {
+	var counts []characterCount
+	total := 0
+	for _, id := range characterIDs(data) {
+		char := data.Characters[id]
+		if n := count(char); n > 0 {
+			counts = append(counts, characterCount{char, n})
+			total += n
+		}
+	}
+	slices.SortStableFunc(counts, func(a, b characterCount) int { return cmp.Compare(b.count, a.count) })
+	return counts, total
+}
+
+// characterLegend labels each character's count in their class colour.
+func characterLegend(counts []characterCount, theme Theme, opts Options) []legendItem {
+	legend := make([]legendItem, len(counts))
+	for i, c := range counts {
+		legend[i] = legendItem{characterName(c.character) + " " + opts.Numbers.Int(c.count), classColour(theme, c.character)}
+	}
+	return legend
+}
+
+// classColour is the theme's colour for a character's class.
+func classColour(theme Theme, char models.Character) string {
+	return cmp.Or(theme.ClassColors[char.Type], unknownColour)
+}
+
+// unknownColour is for classes, and raids the table doesn't have yet.
+const unknownColour = "#aaaaaa"
+
+// characterIDs is the player's character uuids in order, since map order
+// is random and ties should land the same way every time.
+func characterIDs(data models.PlayerData) []string {
+	ids := make([]string, 0, len(data.Characters))
+	for id := range data.Characters {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,200 @@
+package statscard
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/fogleman/gg"
+
+	"wynn_bot/canvas"
+	"wynn_bot/models"
+)
+
+// professionGroups are the professions as the game groups them, in the
+// order the card draws them. The names are the api's keys.
+var professionGroups = []struct {
+	name        string
+	professions []string
+}{
+	{"gathering", []string{"fishing", "woodcutting", "mining", "farming"}},
+	{"crafting", []string{"alchemism", "armouring", "cooking", "jeweling", "scribing", "tailoring", "weaponsmithing", "woodworking"}},
+}
+
+// FindCharacter looks a character up by uuid, nickname or class, the
+// highest level one if several share the class. The nickname is matched
+// without formatting and either's case.
+func FindCharacter(data models.PlayerData, query string) (models.Character, bool) {
+	if char, ok := data.Characters[query]; ok {
+		return char, true
+	}
+	var found models.Character
+	ok := false
+	for _, id := range characterIDs(data) {
+		char := data.Characters[id]
+		if char.Nickname != nil && strings.EqualFold(plainText(*char.Nickname), query) {
+			return char, true
+		}
+		if strings.EqualFold(char.Type, query) && (!ok || char.Level > found.Level) {
+			found, ok = char, true
+		}
+	}
+	return found, ok
+}
+
+// professionRow is one profession's line, and whose level it shows.
+type professionRow struct {
+	name       string
+	character  models.Character
+	profession models.Profession
+	rank       int // leaderboard position, zero if unranked
+}
+
+// professionRows is every profession of char, or of whichever character
+// is furthest in it when char is nil.
+func professionRows(data models.PlayerData, char *models.Character) [][]professionRow {
+	ids := characterIDs(data)
+	groups := make([][]professionRow, len(professionGroups))
+	for i, group := range professionGroups {
+		for _, name := range group.professions {
+			row := professionRow{name: name, rank: data.Ranking.All[name+"Level"]}
+			if char != nil {
+				row.character, row.profession = *char, char.Professions[name]
+			} else {
+				for _, id := range ids {
+					c := data.Characters[id]
+					p := c.Professions[name]
+					if row.character.Type == "" || cmp.Or(cmp.Compare(p.Level, row.profession.Level), cmp.Compare(p.XPPercent, row.profession.XPPercent)) > 0 {
+						row.character, row.profession = c, p
+					}
+				}
+			}
+			groups[i] = append(groups[i], row)
+		}
+	}
+	return groups
+}
+
+// the professions card is a panel per group under the header
+const professionRowHeight = 46
+
+func professionsHeight() int {
+	h := headerHeight + pagePadding
+	for _, group := range professionGroups {
+		h += professionPanelHeight(len(group.professions)) + pagePadding
+	}
+	return h
+}
+
+func professionPanelHeight(rows int) int {
+	return 40 + rows*professionRowHeight
+}
+
+// RenderProfessionsCard draws the levels of char's professions, or each
+// profession's best character if char is nil. Drawing stops with ctx's
+// error once it ends.
+func RenderProfessionsCard(ctx context.Context, data models.PlayerData, char *models.Character, opts Options) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+	card := gg.NewContext(width, professionsHeight())
+	if err := drawProfessionsCard(ctx, card, data, char, opts); err != nil {
+		return nil, err
+	}
+	return card.Image(), nil
+}
+
+// WriteProfessionsCard draws the professions card into w as f, stopping
+// like RenderProfessionsCard.
+func WriteProfessionsCard(ctx context.Context, w io.Writer, f canvas.Format, data models.PlayerData, char *models.Character, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	opts = opts.withDefaults()
+	return canvas.Encode(w, f, width, professionsHeight(), func(card canvas.Canvas) error {
+		return drawProfessionsCard(ctx, card, data, char, opts)
+	})
+}
+
+func drawProfessionsCard(ctx context.Context, card canvas.Canvas, data models.PlayerData, char *models.Character, opts Options) error {
+	theme, num := opts.Theme, opts.Numbers
+	groups := professionRows(data, char)
+
+	subtitle := "best of each character's professions"
+	if char != nil {
+		total := 0
+		for _, p := range char.Professions {
+			total += p.Level
+		}
+		subtitle = characterName(*char) + " · " + num.Int(total) + " profession levels"
+	}
+	if err := drawPage(card, data, theme, professionsHeight(), subtitle); err != nil {
+		return err
+	}
+
+	const left, right = 30.0, width - 30.0
+	y := float64(headerHeight + pagePadding)
+	for g, group := range professionGroups {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rows := groups[g]
+		h := float64(professionPanelHeight(len(rows)))
+		drawPanel(card, theme, y, h)
+
+		if err := card.LoadFontFace(theme.Fonts.Body, 20); err != nil {
+			return err
+		}
+		card.SetColor(theme.Text)
+		card.DrawStringAnchored(group.name, left, y+24, 0, 0.5)
+
+		for i, row := range rows {
+			rowY := y + 40 + float64(i*professionRowHeight)
+			colour := classColour(theme, row.character)
+
+			if err := card.LoadFontFace(theme.Fonts.Body, 16); err != nil {
+				return err
+			}
+			card.SetColor(theme.Text)
+			card.DrawStringAnchored(row.name, left, rowY+12, 0, 0.5)
+			if char == nil && row.character.Type != "" {
+				w, _ := card.MeasureString(row.name)
+				if err := card.LoadFontFace(theme.Fonts.Body, 13); err != nil {
+					return err
+				}
+				card.SetHexColor(colour)
+				card.DrawStringAnchored(characterName(row.character), left+w+10, rowY+13, 0, 0.5)
+			}
+
+			// level, and the leaderboard position before it
+			if err := card.LoadFontFace(theme.Fonts.Display, 20); err != nil {
+				return err
+			}
+			card.SetHexColor(colour)
+			level := "lv " + num.Int(row.profession.Level)
+			card.DrawStringAnchored(level, right, rowY+12, 1, 0.5)
+			levelWidth, _ := card.MeasureString(level)
+			if row.rank > 0 {
+				if err := card.LoadFontFace(theme.Fonts.Body, 13); err != nil {
+					return err
+				}
+				card.SetColor(theme.Text)
+				card.DrawStringAnchored("#"+num.Int(row.rank), right-levelWidth-12, rowY+13, 1, 0.5)
+			}
+
+			// progress to the next level
+			if err := card.LoadFontFace(theme.Fonts.Body, 12); err != nil {
+				return err
+			}
+			drawBar(card, theme, left, rowY+26, right-left-44, 8, []slice{{row.profession.XPPercent, colour}}, 100)
+			card.SetColor(theme.Text)
+			card.DrawStringAnchored(fmt.Sprintf("%d%%", row.profession.XPPercent), right, rowY+30, 1, 0.5)
+		}
+		y += h + pagePadding
+	}
+	return nil
+}
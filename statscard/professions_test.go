@@ -0,0 +1,87 @@
+package statscard
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"wynn_bot/models"
+)
+
+func TestProfessionsCard(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	mage := player.Characters["c2"]
+
+	for name, char := range map[string]*models.Character{"best": nil, "mage": &mage} {
+		t.Run(name, func(t *testing.T) {
+			img, err := RenderProfessionsCard(context.Background(), player, char, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, "professions_"+name, img)
+		})
+	}
+}
+
+func TestProfessionsCardStopsWithContext(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RenderProfessionsCard(ctx, player, nil, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want the context's error", err)
+	}
+}
+
+func TestBestProfessions(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	// level with the mage, and further into it
+	player.Characters["c1"].Professions["cooking"] = models.Profession{Level: 72, XPPercent: 88}
+	groups := professionRows(player, nil)
+
+	best := map[string]string{}
+	for _, group := range groups {
+		for _, row := range group {
+			best[row.name] = row.character.Type
+		}
+	}
+	for name, want := range map[string]string{
+		"fishing":   "MAGE",
+		"mining":    "ARCHER",
+		"alchemism": "MAGE",
+		"cooking":   "ARCHER",
+	} {
+		if best[name] != want {
+			t.Errorf("best %s is %s, want %s", name, best[name], want)
+		}
+	}
+	if got := groups[0][0].rank; got != 4821 {
+		t.Errorf("fishing is ranked %d, want 4821", got)
+	}
+}
+
+func TestFindCharacter(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	c3 := player.Characters["c3"]
+	nickname := "§bTotem Boi"
+	c3.Nickname = &nickname
+	player.Characters["c3"] = c3
+	// a second archer, lower level than c1
+	player.Characters["c5"] = models.Character{Type: "ARCHER", Level: 12}
+
+	for query, want := range map[string]string{
+		"c2":        "MAGE",
+		"totem boi": "SHAMAN",
+		"archer":    "ARCHER",
+	} {
+		char, ok := FindCharacter(player, query)
+		if !ok || char.Type != want {
+			t.Errorf("FindCharacter(%q) = %s, %v, want %s", query, char.Type, ok, want)
+		}
+	}
+	if char, _ := FindCharacter(player, "archer"); char.Level != 106 {
+		t.Errorf("found the level %d archer, want the highest", char.Level)
+	}
+	if _, ok := FindCharacter(player, "assassin"); ok {
+		t.Error("found an assassin the player doesn't have")
+	}
+}
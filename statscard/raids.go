@@ -0,0 +1,268 @@
+package statscard
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fogleman/gg"
+
+	"wynn_bot/canvas"
+	"wynn_bot/models"
+)
+
+// RaidTablePath is where the raids card reads which raids there are, so a
+// new raid only needs a line there. A raid the api sends that isn't in the
+// table still gets a panel, after the known ones.
+const RaidTablePath = "statscard/data/raids.json"
+
+// Raid is how the raids card names a raid and finds its leaderboards.
+type Raid struct {
+	Name              string `json:"name"`              // as the api lists it
+	Short             string `json:"short"`             // the key of Theme.RaidColors
+	Colour            string `json:"colour"`            // when the theme has none
+	CompletionRanking string `json:"completionRanking"` // keys of models.Ranking.All
+	SpeedrunRanking   string `json:"speedrunRanking"`
+}
+
+// RaidTable is the raids in the order they're drawn, and the completion
+// counts the progress bars fill towards.
+type RaidTable struct {
+	Raids      []Raid `json:"raids"`
+	Milestones []int  `json:"milestones"`
+}
+
+// LoadRaidTable reads a raid table from a JSON file.
+func LoadRaidTable(path string) (RaidTable, error) {
+	var table RaidTable
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return table, fmt.Errorf("failed to read raid table: %v", err)
+	}
+	if err := json.Unmarshal(raw, &table); err != nil {
+		return table, fmt.Errorf("failed to decode raid table: %v", err)
+	}
+	slices.Sort(table.Milestones)
+	return table, nil
+}
+
+// raidTable is the table at RaidTablePath, read the first time a card
+// needs it. The cards only read it, raidRows works on a copy.
+var raidTable = sync.OnceValues(func() (RaidTable, error) {
+	return LoadRaidTable(RaidTablePath)
+})
+
+// raidRow is one raid's panel.
+type raidRow struct {
+	raid       Raid
+	colour     string
+	total      int
+	characters []characterCount // most completions first
+}
+
+// raidRows is the table's raids, then any others the player has done by name.
+func raidRows(data models.PlayerData, table RaidTable, theme Theme) []raidRow {
+	raids := slices.Clone(table.Raids)
+	known := map[string]bool{}
+	for _, raid := range raids {
+		known[raid.Name] = true
+	}
+	var unknown []string
+	for _, name := range raidNames(data) {
+		if !known[name] {
+			unknown = append(unknown, name)
+			known[name] = true
+		}
+	}
+	slices.Sort(unknown)
+	for _, name := range unknown {
+		raids = append(raids, Raid{Name: name, Short: initials(name)})
+	}
+
+	rows := make([]raidRow, len(raids))
+	for i, raid := range raids {
+		row := raidRow{raid: raid, colour: raidColour(theme, raid)}
+		row.characters, row.total = characterCounts(data, func(char models.Character) int {
+			return char.Raids.List[raid.Name]
+		})
+		// characters the api leaves out of the list still count towards the total
+		row.total = max(row.total, data.GlobalData.Raids.List[raid.Name])
+		rows[i] = row
+	}
+	return rows
+}
+
+// raidColour is the theme's colour for raid, or the table's if the theme
+// has none.
+func raidColour(theme Theme, raid Raid) string {
+	return cmp.Or(theme.RaidColors[raid.Short], raid.Colour, unknownColour)
+}
+
+// raidNames is every raid the player or any of their characters has done.
+func raidNames(data models.PlayerData) []string {
+	var names []string
+	for name := range data.GlobalData.Raids.List {
+		names = append(names, name)
+	}
+	for _, char := range data.Characters {
+		for name := range char.Raids.List {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// initials stands in for the short name of a raid the table doesn't know.
+func initials(name string) string {
+	var short strings.Builder
+	for _, word := range strings.Fields(name) {
+		short.WriteString(strings.ToLower(word[:1]))
+	}
+	return short.String()
+}
+
+// milestone is the last milestone total reached and the next one, which is
+// zero once they all are.
+func milestone(total int, milestones []int) (int, int) {
+	last := 0
+	for _, m := range milestones {
+		if total < m {
+			return last, m
+		}
+		last = m
+	}
+	return last, 0
+}
+
+// the raids card is a panel per raid under the header
+const raidPanelHeight = 150
+
+func raidsHeight(rows int) int {
+	return headerHeight + pagePadding + rows*(raidPanelHeight+pagePadding)
+}
+
+// RenderRaidsCard draws the player's raid completions, a panel per raid in
+// the table at RaidTablePath. Drawing stops with ctx's error once it ends.
+func RenderRaidsCard(ctx context.Context, data models.PlayerData, opts Options) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	table, err := raidTable()
+	if err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+	rows := raidRows(data, table, opts.Theme)
+	card := gg.NewContext(width, raidsHeight(len(rows)))
+	if err := drawRaidsCard(ctx, card, data, rows, table.Milestones, opts); err != nil {
+		return nil, err
+	}
+	return card.Image(), nil
+}
+
+// WriteRaidsCard draws the raids card into w as f, stopping like
+// RenderRaidsCard.
+func WriteRaidsCard(ctx context.Context, w io.Writer, f canvas.Format, data models.PlayerData, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	table, err := raidTable()
+	if err != nil {
+		return err
+	}
+	opts = opts.withDefaults()
+	rows := raidRows(data, table, opts.Theme)
+	return canvas.Encode(w, f, width, raidsHeight(len(rows)), func(card canvas.Canvas) error {
+		return drawRaidsCard(ctx, card, data, rows, table.Milestones, opts)
+	})
+}
+
+func drawRaidsCard(ctx context.Context, card canvas.Canvas, data models.PlayerData, rows []raidRow, milestones []int, opts Options) error {
+	theme, num := opts.Theme, opts.Numbers
+
+	subtitle := num.Int(data.GlobalData.Raids.Total) + " raids completed"
+	if err := drawPage(card, data, theme, raidsHeight(len(rows)), subtitle); err != nil {
+		return err
+	}
+
+	const left, right = 30.0, width - 30.0
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		y := float64(headerHeight + pagePadding + i*(raidPanelHeight+pagePadding))
+		drawPanel(card, theme, y, raidPanelHeight)
+
+		// name and total
+		card.SetHexColor(row.colour)
+		card.DrawCircle(left+6, y+26, 6)
+		card.Fill()
+		if err := card.LoadFontFace(theme.Fonts.Body, 20); err != nil {
+			return err
+		}
+		card.SetColor(theme.Text)
+		card.DrawStringAnchored(row.raid.Name, left+20, y+26, 0, 0.5)
+		if err := card.LoadFontFace(theme.Fonts.Display, 24); err != nil {
+			return err
+		}
+		card.SetHexColor(row.colour)
+		card.DrawStringAnchored(num.Int(row.total), right, y+26, 1, 0.5)
+
+		// leaderboards
+		if err := card.LoadFontFace(theme.Fonts.Body, 14); err != nil {
+			return err
+		}
+		card.SetColor(theme.Text)
+		card.DrawStringAnchored(raidRankings(row.raid, data.Ranking, opts), left, y+52, 0, 0.5)
+
+		// who did them
+		legend := characterLegend(row.characters, theme, opts)
+		parts := make([]slice, len(row.characters))
+		for j, c := range row.characters {
+			parts[j] = slice{c.count, legend[j].color}
+		}
+		drawBar(card, theme, left, y+66, right-left, 12, parts, row.total)
+		if len(legend) == 0 {
+			card.SetColor(theme.Text)
+			card.DrawStringAnchored("not completed yet", left, y+92, 0, 0.5)
+		} else {
+			drawLegend(card, theme, legend, left, y+92, right-left)
+		}
+
+		// the next milestone
+		last, next := milestone(row.total, milestones)
+		if next == 0 {
+			drawBar(card, theme, left, y+110, right-left, 8, []slice{{1, row.colour}}, 1)
+			card.SetHexColor(row.colour)
+			card.DrawStringAnchored("every milestone reached", left, y+132, 0, 0.5)
+			continue
+		}
+		drawBar(card, theme, left, y+110, right-left, 8, []slice{{row.total - last, row.colour}}, next-last)
+		card.SetHexColor(row.colour)
+		card.DrawStringAnchored(fmt.Sprintf("%s more to %s", num.Int(next-row.total), num.Int(next)), left, y+132, 0, 0.5)
+		card.DrawStringAnchored(fmt.Sprintf("%d%%", 100*(row.total-last)/(next-last)), right, y+132, 1, 0.5)
+	}
+	return nil
+}
+
+// raidRankings is the line of leaderboard positions under a raid's name.
+func raidRankings(raid Raid, ranking models.Ranking, opts Options) string {
+	var ranks []string
+	if n := ranking.All[raid.CompletionRanking]; n > 0 {
+		ranks = append(ranks, "completions #"+opts.Numbers.Int(n))
+	}
+	if n := ranking.All[raid.SpeedrunRanking]; n > 0 {
+		ranks = append(ranks, "speedrun #"+opts.Numbers.Int(n))
+	}
+	if len(ranks) == 0 {
+		return "not on the leaderboards"
+	}
+	return strings.Join(ranks, " · ")
+}
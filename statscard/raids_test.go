@@ -0,0 +1,121 @@
+package statscard
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"wynn_bot/models"
+)
+
+func TestRaidsCard(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+
+	for _, theme := range []string{"dark", "light"} {
+		t.Run(theme, func(t *testing.T) {
+			th, err := GetTheme(theme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := RenderRaidsCard(context.Background(), player, Options{Theme: th})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, "raids_"+theme, img)
+		})
+	}
+}
+
+func TestRaidsCardStopsWithContext(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RenderRaidsCard(ctx, player, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want the context's error", err)
+	}
+}
+
+func TestRaidRowsFollowTheTable(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	// a raid released after the table was written
+	player.GlobalData.Raids.List["The Wartorn Palace"] = 3
+	char := player.Characters["c3"]
+	char.Raids.List["The Wartorn Palace"] = 3
+	player.Characters["c3"] = char
+
+	table, err := LoadRaidTable(RaidTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := raidRows(player, table, Themes[DefaultThemeName])
+
+	var names []string
+	for _, row := range rows {
+		names = append(names, row.raid.Short)
+	}
+	if want := []string{"nog", "nol", "tcc", "tna", "twp"}; !slices.Equal(names, want) {
+		t.Errorf("rows are %v, want %v", names, want)
+	}
+	if got := rows[4].colour; got != unknownColour {
+		t.Errorf("an unknown raid is coloured %s, want %s", got, unknownColour)
+	}
+
+	tcc := rows[2]
+	if tcc.total != 185 {
+		t.Errorf("tcc total is %d, want 185", tcc.total)
+	}
+	var counts []int
+	for _, c := range tcc.characters {
+		counts = append(counts, c.count)
+	}
+	if want := []int{100, 80, 5}; !slices.Equal(counts, want) {
+		t.Errorf("tcc characters are %v, want %v", counts, want)
+	}
+}
+
+func TestRaidSplitFollowsTheTable(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	player.GlobalData.Raids.List["The Wartorn Palace"] = 3
+
+	table, err := LoadRaidTable(RaidTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.Raids = append(table.Raids, Raid{Name: "The Wartorn Palace", Short: "twp"})
+	old := raidTable
+	t.Cleanup(func() { raidTable = old })
+	raidTable = func() (RaidTable, error) { return table, nil }
+
+	split := raidSplit(player.GlobalData.Raids, Options{}.withDefaults().Numbers)
+	if lines := strings.Split(split, "\n"); len(lines) != len(table.Raids)+1 || !strings.HasPrefix(lines[len(lines)-1], "twp 3 ") {
+		t.Errorf("got %q, want a line per raid in the table ending with twp", split)
+	}
+}
+
+func TestRaidRankings(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	nog := Raid{CompletionRanking: "grootslangCompletion", SpeedrunRanking: "grootslangSrPlayers"}
+
+	if got, want := raidRankings(nog, player.Ranking, Options{}.withDefaults()), "completions #2,210 · speedrun #512"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := raidRankings(Raid{}, player.Ranking, Options{}.withDefaults()), "not on the leaderboards"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMilestone(t *testing.T) {
+	milestones := []int{10, 25, 50}
+	for _, tc := range []struct{ total, last, next int }{
+		{0, 0, 10},
+		{10, 10, 25},
+		{49, 25, 50},
+		{50, 50, 0},
+	} {
+		if last, next := milestone(tc.total, milestones); last != tc.last || next != tc.next {
+			t.Errorf("milestone(%d) = %d, %d, want %d, %d", tc.total, last, next, tc.last, tc.next)
+		}
+	}
+}
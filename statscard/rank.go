@@ -0,0 +1,161 @@
+package statscard
+
+import (
+	"image"
+	"image/color"
+	"log"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"wynn_bot/assets"
+	"wynn_bot/models"
+
+	"github.com/fogleman/gg"
+)
+
+const rankDir = "statscard/ranks_upscale"
+
+// the height of the upscaled badges, generated ones match it
+const badgeHeight = 36
+
+// rankAliases maps the spellings ShortenedRank and Rank use to badge names.
+var rankAliases = map[string]string{
+	"player":    "none",
+	"admin":     "administrator",
+	"mod":       "moderator",
+	"gm":        "gamemaster",
+	"qaassist":  "qa",
+	"musician":  "music",
+	"youtube":   "media",
+	"youtuber":  "media",
+	"itemteam":  "item",
+	"buildteam": "builder",
+}
+
+// rankCandidates lists badge names to try, best source first: the badge url,
+// then SupportRank, ShortenedRank and Rank.
+func rankCandidates(data models.PlayerData) []string {
+	var names []string
+	if data.RankBadge != nil {
+		names = append(names, badgeName(*data.RankBadge))
+	}
+	if data.SupportRank != nil {
+		names = append(names, normalizeRank(*data.SupportRank))
+	}
+	if data.ShortenedRank != nil {
+		names = append(names, normalizeRank(*data.ShortenedRank))
+	}
+	names = append(names, normalizeRank(data.Rank))
+
+	var candidates []string
+	for _, name := range names {
+		if name != "" {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
+// badgeName pulls "hero" out of urls like "/nextgen/badges/rank_hero.svg",
+// with or without a host, query or extension.
+func badgeName(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+	base := path.Base(u.Path)
+	if base == "." || base == "/" {
+		return ""
+	}
+	base = strings.TrimSuffix(base, path.Ext(base))
+	return normalizeRank(strings.TrimPrefix(base, "rank_"))
+}
+
+func normalizeRank(rank string) string {
+	rank = strings.ToLower(strings.TrimSpace(rank))
+	rank = strings.ReplaceAll(rank, "+", "plus")
+	rank = strings.NewReplacer(" ", "", "_", "", "-", "").Replace(rank)
+	if alias, ok := rankAliases[rank]; ok {
+		return alias
+	}
+	return rank
+}
+
+// ResolveRankBadge picks the badge image for a player. Ranks without a
+// bundled image get a generated text badge, so this never fails the card.
+func ResolveRankBadge(data models.PlayerData) image.Image {
+	for _, name := range rankCandidates(data) {
+		// "none" only means the player has no rank once nothing better is known
+		if name == "none" {
+			continue
+		}
+		img, err := LoadImage(filepath.Join(rankDir, assets.RankFile(name)))
+		if err == nil {
+			return img
+		}
+	}
+
+	label := rankLabel(data)
+	if label == "" {
+		if img, err := LoadImage(filepath.Join(rankDir, assets.RankFile("none"))); err == nil {
+			return img
+		}
+		label = "?"
+	}
+
+	log.Printf("no badge for rank %q, generating one", label)
+	return textBadge(label, data.LegacyRankColour)
+}
+
+// rankLabel is what a generated badge says, empty for players without a rank.
+func rankLabel(data models.PlayerData) string {
+	for _, rank := range []*string{data.ShortenedRank, data.SupportRank, &data.Rank} {
+		if rank != nil && *rank != "" && normalizeRank(*rank) != "none" {
+			return strings.ToUpper(*rank)
+		}
+	}
+	return ""
+}
+
+// textBadge draws a badge in the style of the bundled ones: a bordered
+// plate in the rank colour with the name in the pixel font.
+func textBadge(label string, colours *models.RankColour) image.Image {
+	fill := color.RGBA{R: 110, G: 110, B: 110, A: 255}
+	border := color.RGBA{R: 60, G: 60, B: 60, A: 255}
+	if colours != nil {
+		fill = hexColor(colours.Main)
+		border = hexColor(colours.Sub)
+	}
+
+	face, err := gg.LoadFontFace(defaultFonts.Display, 26)
+	if err != nil {
+		log.Printf("failed to load badge font: %v", err)
+	}
+
+	measure := gg.NewContext(1, 1)
+	if face != nil {
+		measure.SetFontFace(face)
+	}
+	textWidth, _ := measure.MeasureString(label)
+
+	w := int(textWidth) + 24
+	badge := gg.NewContext(w, badgeHeight)
+	badge.SetColor(border)
+	badge.DrawRectangle(0, 4, float64(w), badgeHeight-8)
+	badge.DrawRectangle(4, 0, float64(w-8), badgeHeight)
+	badge.Fill()
+	badge.SetColor(fill)
+	badge.DrawRectangle(4, 4, float64(w-8), badgeHeight-8)
+	badge.Fill()
+
+	if face != nil {
+		badge.SetFontFace(face)
+	}
+	badge.SetColor(border)
+	badge.DrawStringAnchored(label, float64(w)/2+2, badgeHeight/2+2, 0.5, 0.4)
+	badge.SetColor(color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	badge.DrawStringAnchored(label, float64(w)/2, badgeHeight/2, 0.5, 0.4)
+	return badge.Image()
+}
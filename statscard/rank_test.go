@@ -0,0 +1,62 @@
+package statscard
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"wynn_bot/assets"
+	"wynn_bot/models"
+)
+
+// An alias to a badge that isn't bundled would skip the generated badge the
+// rank would otherwise get.
+func TestRankAliasesHaveBadges(t *testing.T) {
+	for rank, badge := range rankAliases {
+		if _, err := LoadImage(filepath.Join(rankDir, assets.RankFile(badge))); err != nil {
+			t.Errorf("%s maps to %s, which has no badge: %v", rank, badge, err)
+		}
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name string
+		data models.PlayerData
+		want []string
+	}{
+		{"badge url first", models.PlayerData{
+			RankBadge:     str("https://cdn.wynncraft.com/nextgen/badges/rank_hero.svg?v=2"),
+			ShortenedRank: str("HERO"),
+			Rank:          "Player",
+		}, []string{"hero", "hero", "none"}},
+		{"support rank", models.PlayerData{SupportRank: str("vip+"), Rank: "Player"}, []string{"vipplus", "none"}},
+		{"staff alias", models.PlayerData{ShortenedRank: str("Admin"), Rank: "Administrator"}, []string{"administrator", "administrator"}},
+		{"no badge", models.PlayerData{ShortenedRank: str("Artist"), Rank: "Artist"}, []string{"artist", "artist"}},
+		{"empty", models.PlayerData{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rankCandidates(tt.data); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRankBadge(t *testing.T) {
+	str := func(s string) *string { return &s }
+	hero, err := LoadImage(filepath.Join(rankDir, assets.RankFile("hero")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ResolveRankBadge(models.PlayerData{SupportRank: str("hero")}); got.Bounds() != hero.Bounds() {
+		t.Errorf("hero got a %v badge, want the bundled %v one", got.Bounds(), hero.Bounds())
+	}
+	// a rank without a bundled badge gets one drawn with its name
+	if got := ResolveRankBadge(models.PlayerData{ShortenedRank: str("Artist"), Rank: "Artist"}); got.Bounds().Dy() != badgeHeight {
+		t.Errorf("artist got a %v badge, want a generated one", got.Bounds())
+	}
+}
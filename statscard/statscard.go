@@ -1,60 +1,33 @@
 package statscard
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"fmt"
+	"hash/fnv"
 	"image"
 	"image/color"
 	"image/png"
+	"io"
+	"log"
 	"math"
 	"math/rand"
-	"net/http"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sort"
 	"strings"
-	"time"
 
+	"wynn_bot/avatar"
+	"wynn_bot/banner"
+	"wynn_bot/canvas"
+	"wynn_bot/humanize"
+	"wynn_bot/mctext"
 	"wynn_bot/models"
+	"wynn_bot/timefmt"
 
 	"github.com/fogleman/gg"
 )
 
-var colorMap = map[string]color.RGBA{
-	"BLACK":      {R: 21, G: 21, B: 24, A: 255},
-	"RED":        {R: 129, G: 34, B: 28, A: 255},
-	"GREEN":      {R: 69, G: 91, B: 16, A: 255},
-	"BROWN":      {R: 96, G: 62, B: 37, A: 255},
-	"BLUE":       {R: 43, G: 49, B: 123, A: 255},
-	"PURPLE":     {R: 100, G: 37, B: 135, A: 255},
-	"CYAN":       {R: 16, G: 114, B: 114, A: 255},
-	"SILVER":     {R: 115, G: 115, B: 111, A: 255},
-	"GRAY":       {R: 52, G: 57, B: 60, A: 255},
-	"PINK":       {R: 179, G: 102, B: 125, A: 255},
-	"LIME":       {R: 93, G: 145, B: 22, A: 255},
-	"YELLOW":     {R: 186, G: 158, B: 45, A: 255},
-	"LIGHT_BLUE": {R: 42, G: 131, B: 160, A: 255},
-	"MAGENTA":    {R: 145, G: 57, B: 138, A: 255},
-	"ORANGE":     {R: 180, G: 93, B: 21, A: 255},
-	"WHITE":      {R: 182, G: 187, B: 186, A: 255},
-}
-
-const darkenFactor = 0.5
-
-var darkenedMap = func() map[string]color.RGBA {
-	darkened := make(map[string]color.RGBA)
-
-	for name, col := range colorMap {
-		darkened[name] = color.RGBA{
-			R: uint8(float64(col.R) * darkenFactor),
-			G: uint8(float64(col.G) * darkenFactor),
-			B: uint8(float64(col.B) * darkenFactor),
-			A: col.A, // Keep alpha unchanged
-		}
-	}
-
-	return darkened
-}()
-
 const width, height = 562, 952 // magic numbers
 const headerWidth, headerHeight = width, 102
 const imageWidth, imageHeight = width / 2, 434
@@ -63,26 +36,6 @@ const imageWidth, imageHeight = width / 2, 434
 const bannerWidth, bannerHeight = width - imageWidth, height - headerHeight - footerHeight
 const footerHidth, footerHeight = width, 262
 
-func RecolorImage(img image.Image, recolor color.RGBA) image.Image {
-	bounds := img.Bounds()
-	recoloredImg := image.NewRGBA(bounds)
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			originalColor := img.At(x, y)
-			_, _, _, alpha := originalColor.RGBA() // Preserve alpha channel
-			recolored := color.RGBA{
-				R: uint8(recolor.R),
-				G: uint8(recolor.G),
-				B: uint8(recolor.B),
-				A: uint8(alpha >> 8), // Alpha is in the high byte
-			}
-			recoloredImg.Set(x, y, recolored)
-		}
-	}
-	return recoloredImg
-}
-
 func LoadImage(filePath string) (image.Image, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -97,172 +50,276 @@ func LoadImage(filePath string) (image.Image, error) {
 	return img, nil
 }
 
-func ParseTime(rawTime string) string {
-	parsedTime, err := time.Parse(time.RFC3339Nano, rawTime)
-	if err != nil {
-		fmt.Println("Error parsing time:", err)
-		return ""
-	}
+// noGuildBanner is drawn for players without a guild.
+var noGuildBanner = models.Banner{
+	Base: "SILVER",
+	// modify to customize banner if no guild
+	Layers: []models.BannerLayer{
+		{Colour: "GRAY", Pattern: "BORDER"},
+		{Colour: "GRAY", Pattern: "MOJANG"},
+	},
+}
+
+// slice is one part of a donut chart, slices are drawn in the order given.
+type slice struct {
+	value int
+	color string
+}
 
-	return parsedTime.Format("Jan 02, 2006")
+// ChartOptions sets where the donut charts start. The zero value starts them
+// at 12 o'clock.
+type ChartOptions struct {
+	// StartAngle is where the first slice begins, in radians clockwise from 12 o'clock.
+	StartAngle float64
+	// Seed picks the start angle instead when non-zero, so cards can differ
+	// between players while each player's card stays the same between renders.
+	Seed int64
+}
 
+// SeedFor derives a chart seed from a string such as a player's uuid.
+func SeedFor(s string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return int64(h.Sum64())
 }
 
-func TimeAgo(isoTimestamp string) string {
-	// Parse the ISO 8601 timestamp
-	parsedTime, err := time.Parse(time.RFC3339Nano, isoTimestamp)
-	if err != nil {
-		return "Invalid timestamp"
+func (c ChartOptions) startAngle() float64 {
+	angle := c.StartAngle
+	if c.Seed != 0 {
+		angle = rand.New(rand.NewSource(c.Seed)).Float64() * 2 * math.Pi
 	}
+	return angle - math.Pi/2
+}
 
-	// Calculate the difference
-	now := time.Now()
-	duration := now.Sub(parsedTime)
+func drawPieChart(card canvas.Canvas, x, y, outerRadius, innerRadius float64, slices []slice, startAngle float64) {
+	total := 0
+	for _, s := range slices {
+		total += s.value
+	}
 
-	// Helper function for pluralization
-	pluralize := func(value int, unit string) string {
-		if value == 1 {
-			return fmt.Sprintf("%d %s ago", value, unit) // Singular
-		}
-		return fmt.Sprintf("%d %ss ago", value, unit) // Plural
-	}
-
-	// Determine the appropriate time unit
-	if duration < time.Minute {
-		return pluralize(int(duration.Seconds()), "second")
-	} else if duration < time.Hour {
-		return pluralize(int(duration.Minutes()), "minute")
-	} else if duration < 24*time.Hour {
-		return pluralize(int(duration.Hours()), "hour")
-	} else if duration < 30*24*time.Hour {
-		return pluralize(int(duration.Hours()/24), "day")
-	} else if duration < 12*30*24*time.Hour {
-		return pluralize(int(duration.Hours()/(24*30)), "month")
-	} else {
-		return pluralize(int(duration.Hours()/(24*365)), "year")
+	if total == 0 {
+		return
 	}
-}
 
-func CreateBanner(data models.PlayerData) (*gg.Context, *models.GuildData, error) {
-	apiURL := "https://api.wynncraft.com/v3/guild/%s"
+	for _, s := range slices {
+		if s.value > 0 {
+			percentage := float64(s.value) / float64(total)
+			endAngle := startAngle + (percentage * 2 * math.Pi)
 
-	var bannerBase string
-	var bannerLayers []models.BannerLayer
-	var guildData *models.GuildData
+			card.SetHexColor(s.color)
+			card.MoveTo(x, y)
+			card.DrawArc(x, y, outerRadius, startAngle, endAngle)
+			card.LineTo(x+innerRadius*math.Cos(endAngle), y+innerRadius*math.Sin(endAngle))
+			card.DrawArc(x, y, innerRadius, endAngle, startAngle)
+			card.ClosePath()
+			card.Fill()
 
-	if data.Guild != nil {
-		guild := data.Guild.Name
-		url := fmt.Sprintf(apiURL, guild)
-		resp, err := http.Get(url)
-		if err != nil {
-			return nil, nil, fmt.Errorf("cannot access url")
-		} else {
-			defer resp.Body.Close()
+			startAngle = endAngle
+		}
+	}
+}
 
-			// var guildData models.GuildData
-			err = json.NewDecoder(resp.Body).Decode(&guildData)
-			banner := gg.NewContext(bannerWidth, bannerHeight)
+// the stats card has room for this many raid lines above the leaderboards
+const statsRaidLines = 4
 
-			if err != nil {
-				banner.SetColor(darkenedMap["SILVER"])
-				banner.Clear()
-			} else {
-				// banner := gg.NewContext(bannerWidth, bannerHeight)
-				bannerBase = guildData.Banner.Base
-				bannerLayers = guildData.Banner.Layers
-			}
+// cardText is a string the stats card draws at x, y. The card keeps them in
+// slices rather than maps so they're drawn in the same order every time,
+// which an SVG shows.
+type cardText struct {
+	text string
+	x, y float64
+}
 
-		}
-	} else {
-		bannerBase = "SILVER"
-		bannerLayers = []models.BannerLayer{}
+// raidLine is one raid's line in the stats card's raid split.
+type raidLine struct {
+	short  string
+	count  int
+	colour string
+}
 
-		// modify to customize banner if no guild
-		bannerLayers = append(bannerLayers,
-			models.BannerLayer{Colour: "GRAY", Pattern: "BORDER"},
-			models.BannerLayer{Colour: "GRAY", Pattern: "MOJANG"},
-		)
+// statsRaidSplit is a line per raid in table, or once there are more than
+// fit, the player's most completed ones and a line for all the others,
+// labelled with how many raids it stands for.
+func statsRaidSplit(table RaidTable, raids models.RaidSummary, theme Theme) []raidLine {
+	lines := make([]raidLine, len(table.Raids))
+	for i, raid := range table.Raids {
+		lines[i] = raidLine{raid.Short, raids.List[raid.Name], raidColour(theme, raid)}
+	}
+	if len(lines) <= statsRaidLines {
+		return lines
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].count > lines[j].count })
+	rest := lines[statsRaidLines-1:]
+	other := raidLine{short: fmt.Sprintf("+%d", len(rest)), colour: unknownColour}
+	for _, line := range rest {
+		other.count += line.count
 	}
-	banner := gg.NewContext(bannerWidth, bannerHeight)
+	return append(lines[:statsRaidLines-1], other)
+}
 
-	banner.SetColor(darkenedMap[bannerBase])
-	banner.Clear()
-	banner.Scale(bannerWidth/160.0, bannerHeight/320.0)
+// percentages splits 100 between values by their share, rounding so the
+// parts still add up to 100.
+func percentages(values []int) []int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	parts := make([]int, len(values))
+	if total == 0 {
+		return parts
+	}
 
-	for _, layer := range bannerLayers {
-		color := darkenedMap[layer.Colour]
-		imagePath := fmt.Sprintf("statscard/banner/%s.png", layer.Pattern)
-		layerBase, err := LoadImage(imagePath)
-		if err != nil {
-			return nil, nil, fmt.Errorf("problem loading image")
-		}
-		recoloredImage := RecolorImage(layerBase, color)
-		banner.DrawImage(recoloredImage, 0, 0)
+	type remainder struct {
+		index int
+		frac  float64
 	}
-	if data.Guild != nil {
-		return banner, guildData, nil
-	} else {
-		return banner, nil, nil
+	remainders := make([]remainder, len(values))
+	left := 100
+	for i, v := range values {
+		exact := float64(v) * 100 / float64(total)
+		parts[i] = int(exact)
+		left -= parts[i]
+		remainders[i] = remainder{i, exact - float64(parts[i])}
 	}
+	sort.SliceStable(remainders, func(a, b int) bool { return remainders[a].frac > remainders[b].frac })
+	for _, r := range remainders[:left] {
+		parts[r.index]++
+	}
+	return parts
 }
 
-func formatNumber(n float64) string {
-	if n >= 1e9 {
-		return fmt.Sprintf("%.1fB", n/1e9)
-	} else if n >= 1e6 {
-		return fmt.Sprintf("%.1fM", n/1e6)
-	} else if n >= 1e3 {
-		return fmt.Sprintf("%.1fK", n/1e3)
-	} else {
-		return fmt.Sprintf("%.1f", n) // For numbers less than 1, use default formatting
-	}
+// guildMember finds a player in the guild's member list, which is split by rank.
+func guildMember(guild *models.GuildData, username, rank string) models.MemberInfo {
+	// this is such a stupid data structure but idk if fixing it to solve the problem once is worth it
+	switch rank {
+	case "OWNER":
+		return guild.Members.Owner[username]
+	case "CHIEF":
+		return guild.Members.Chief[username]
+	case "STRATEGIST":
+		return guild.Members.Strategist[username]
+	case "CAPTAIN":
+		return guild.Members.Captain[username]
+	case "RECRUITER":
+		return guild.Members.Recruiter[username]
+	case "RECRUIT":
+		return guild.Members.Recruit[username]
+	}
+	return models.MemberInfo{}
 }
 
-func drawPieChart(card *gg.Context, x, y, outerRadius, innerRadius float64, data map[string]int, colors map[string]string) {
-	total := 0
-	for _, value := range data {
-		total += value
+// Options controls how a card is drawn, the zero value gives the default look.
+type Options struct {
+	Theme   Theme
+	Time    *timefmt.Formatter // UTC and en-US if nil
+	Numbers humanize.Options   // humanize.Default if zero
+	Chart   ChartOptions
+	Avatars avatar.Provider // avatar.Default if nil
+	// Animation is how an animated card moves, a milestone's if nil.
+	Animation *Animation
+}
+
+// CreateStatsCard draws the card and saves it to outputDir/fileName, animated
+// if the name ends in .gif and as vectors for .svg. guild is the player's
+// guild, nil if they have none or it couldn't be fetched.
+func CreateStatsCard(ctx context.Context, data models.PlayerData, guild *models.GuildData, opts Options, outputDir string, fileName string) error {
+	format := canvas.FormatPNG
+	switch filepath.Ext(fileName) {
+	case canvas.FormatGIF.Ext():
+		format = canvas.FormatGIF
+	case canvas.FormatSVG.Ext():
+		format = canvas.FormatSVG
 	}
 
-	if total == 0 {
-		return
+	var buf bytes.Buffer
+	if err := WriteStatsCard(ctx, &buf, format, data, guild, opts); err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputDir+"/"+fileName, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to save image: %v", err)
 	}
+	return nil
+}
 
-	startAngle := rand.Float64() * 2 * math.Pi
-	for key, value := range data {
-		if value > 0 {
-			percentage := float64(value) / float64(total)
-			endAngle := startAngle + (percentage * 2 * math.Pi)
+// RenderStatsCard draws the card, for callers that send it without saving.
+// ctx bounds the avatar fetch, drawing stops with ctx's error once it ends.
+func RenderStatsCard(ctx context.Context, data models.PlayerData, guild *models.GuildData, opts Options) (image.Image, error) {
+	avatarImg, err := fetchAvatar(ctx, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	card := gg.NewContext(width, height)
+	if err := drawStatsCard(card, data, guild, opts, avatarImg, still); err != nil {
+		return nil, err
+	}
+	return card.Image(), nil
+}
 
-			card.SetHexColor(colors[key])
-			card.MoveTo(x, y)
-			card.DrawArc(x, y, outerRadius, startAngle, endAngle)
-			card.LineTo(x+innerRadius*math.Cos(endAngle), y+innerRadius*math.Sin(endAngle))
-			card.DrawArc(x, y, innerRadius, endAngle, startAngle)
-			card.ClosePath()
-			card.Fill()
+// WriteStatsCard draws the card and writes it to w as f, an SVG keeps the
+// shapes and text as vectors. canvas.FormatGIF animates it as opts.Animation
+// says, or as for a milestone if that's nil.
+func WriteStatsCard(ctx context.Context, w io.Writer, f canvas.Format, data models.PlayerData, guild *models.GuildData, opts Options) error {
+	avatarImg, err := fetchAvatar(ctx, data, opts)
+	if err != nil {
+		return err
+	}
+	if f == canvas.FormatGIF {
+		return animateStatsCard(ctx, w, data, guild, opts, avatarImg)
+	}
+	return canvas.Encode(w, f, width, height, func(card canvas.Canvas) error {
+		return drawStatsCard(card, data, guild, opts, avatarImg, still)
+	})
+}
 
-			startAngle = endAngle
-		}
+// fetchAvatar gets the player's render sized for the card, or a silhouette
+// if no provider has one. It fails only with ctx's error.
+func fetchAvatar(ctx context.Context, data models.PlayerData, opts Options) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	avatars := opts.Avatars
+	if avatars == nil {
+		avatars = avatar.Default
+	}
+	avatarImg, err := avatars.Avatar(ctx, avatar.Player{Username: data.Username, UUID: data.UUID})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// nobody is waiting for the card any more, don't draw a silhouette for it
+		return nil, ctxErr
+	}
+	if err != nil {
+		log.Printf("drawing a silhouette for %s: %v", data.Username, err)
+		avatarImg = avatar.Silhouette()
 	}
+
+	// fit by height like nmsr's renders, other providers may differ
+	avatarW, avatarH := float64(avatarImg.Bounds().Dx()), float64(avatarImg.Bounds().Dy())
+	scaling := min(imageWidth/avatarW, imageHeight/avatarH) * 0.9
+	scaled := gg.NewContext(int(math.Round(avatarW*scaling)), int(math.Round(avatarH*scaling)))
+	scaled.Scale(scaling, scaling)
+	scaled.DrawImage(avatarImg, -avatarImg.Bounds().Min.X, -avatarImg.Bounds().Min.Y)
+	return scaled.Image(), nil
 }
 
-func CreateStatsCard(data models.PlayerData, outputDir string, fileName string) error {
+// drawStatsCard lays the card out as it looks at f, the static card is still.
+func drawStatsCard(card canvas.Canvas, data models.PlayerData, guild *models.GuildData, opts Options, avatarImg image.Image, f frame) error {
 
-	card := gg.NewContext(width, height)
+	opts = opts.withDefaults()
+	theme, timeFormat, num := opts.Theme, opts.Time, opts.Numbers
 
 	// background
-	card.SetColor(color.RGBA{R: 19, G: 0, B: 25, A: 255})
+	card.SetColor(theme.Background)
 	card.Clear() // this only ends up in the footer tbh
 
-	background, err := LoadImage("statscard/images/background.png")
-	if err != nil {
-		return fmt.Errorf("failed to load background: %v", err)
+	if theme.BackgroundImage != "" {
+		background, err := LoadImage(theme.BackgroundImage)
+		if err != nil {
+			return fmt.Errorf("failed to load background: %v", err)
+		}
+		card.DrawImage(background, 0, 0)
 	}
-	card.DrawImage(background, 0, 0)
 
 	// boxes
-	card.SetColor(color.RGBA{R: 0, G: 0, B: 0, A: 120})
+	card.SetColor(theme.Box)
 	card.DrawRectangle(0, 0, headerWidth, headerHeight)
 
 	card.DrawRectangle(0, headerHeight+imageHeight, imageWidth, bannerHeight-imageHeight)
@@ -270,44 +327,31 @@ func CreateStatsCard(data models.PlayerData, outputDir string, fileName string)
 
 	// player avatar
 
-	avatarImg, err := fetchAvatar(data.Username)
-	if err != nil {
-		return fmt.Errorf("failed to fetch avatar: %v", err)
-	}
-
-	scaling := min(imageWidth/512.0, imageHeight/869.0) * 0.9
-	avatar := gg.NewContext(int(math.Round(512*scaling)), int(math.Round(869*scaling)))
-	avatar.Scale(scaling, scaling)
-	avatar.DrawImage(avatarImg, 0, 0)
-
-	card.DrawImageAnchored(avatar.Image(), imageWidth/2, headerHeight+imageHeight/2, 0.5, 0.5)
+	card.DrawImageAnchored(avatarImg, imageWidth/2, headerHeight+imageHeight/2, 0.5, 0.5)
 
 	// guild background
-	banner, guild, err := CreateBanner(data)
-	if err != nil {
-		return fmt.Errorf("error creating banner %v", err)
+	bannerData := noGuildBanner
+	if guild != nil {
+		bannerData = guild.Banner
 	}
+	card.DrawImage(banner.Default.RenderFramed(bannerData, bannerWidth, bannerHeight, banner.Darkened), imageWidth, headerHeight)
 
-	card.DrawImage(banner.Image(), imageWidth, headerHeight)
+	if label := banner.Label(bannerData); label != "" {
+		if err := card.LoadFontFace(theme.Fonts.Body, 11); err != nil {
+			panic(err)
+		}
+		card.SetColor(theme.Text)
+		if frame, ok := banner.FrameFor(bannerData.Tier); ok {
+			card.SetColor(frame.Inner)
+		}
+		card.DrawStringAnchored(label, width-30, headerHeight+18, 1, 0.5)
+	}
 
 	// header content
 
 	// rank badge
 
-	rankImg, err := LoadImage("statscard/ranks_upscale/rank_none.png")
-	if err != nil {
-		return fmt.Errorf("error loading none badge: %v", err)
-	}
-
-	if data.RankBadge != nil {
-		rankBadge := (*data.RankBadge)[15 : len(*data.RankBadge)-4]
-		rankImg, err = LoadImage(fmt.Sprintf("statscard/ranks_upscale/%s.png", rankBadge))
-		if err != nil {
-			return fmt.Errorf("error loading rank badge: %v", err)
-		}
-	} else {
-
-	}
+	rankImg := ResolveRankBadge(data)
 	badge := gg.NewContext(int(math.Round(headerWidth/2.0)), int(math.Round(headerHeight/3.0)))
 	// badge.Scale(math.Round(headerHeight/30.0), math.Round(headerHeight/30.0))
 	badge.DrawImage(rankImg, 0, 0)
@@ -316,68 +360,99 @@ func CreateStatsCard(data models.PlayerData, outputDir string, fileName string)
 
 	// text
 
-	if data.LegacyRankColour != nil {
-		card.SetHexColor(data.LegacyRankColour.Sub)
-	} else {
-		card.SetColor(color.RGBA{R: 221, G: 225, B: 218, A: 255})
-	}
+	nameStyle := usernameStyle(data, theme)
 
-	if err := card.LoadFontFace("statscard/fonts/minecraft.ttf", 42); err != nil {
+	if err := card.LoadFontFace(theme.Fonts.Display, 42); err != nil {
 		panic(err)
 	}
-	card.DrawStringAnchored(data.Username, float64(rankImg.Bounds().Max.X)+30, 30, 0, 0.4)
+	nameX := float64(rankImg.Bounds().Max.X) + 30
+	nameWidth := mctext.Draw(card, []mctext.Segment{{Text: data.Username}}, nameStyle, nameX, 30, 0, 0.4)
 
+	if data.Veteran != nil && *data.Veteran {
+		if err := card.LoadFontFace(theme.Fonts.Display, 20); err != nil {
+			panic(err)
+		}
+		mctext.Draw(card, []mctext.Segment{{Text: "VETERAN", Color: mctext.Gold, Bold: true}},
+			mctext.Style{Shadow: true}, nameX+nameWidth+12, 30, 0, 0.4)
+	}
+
+	// line breaks don't work with gg for some reason
+	// a timestamp that doesn't parse drops its line rather than printing junk on the card
 	subtitle1 := ""
-	subtitle2 := "" // line breaks don't work with gg for some reason
-	subtitle1 += "first joined " + ParseTime(data.FirstJoin)
-	if data.Online {
-		subtitle2 += "currently online on world " + *data.Server
+	if firstJoin, err := timeFormat.Date(data.FirstJoin); err == nil {
+		subtitle1 = "first joined " + firstJoin
 	} else {
-		subtitle2 += "last seen " + TimeAgo(data.LastJoin)
+		log.Printf("skipping first join: %v", err)
+	}
+	subtitle2 := ""
+	if data.Online && data.Server != nil {
+		subtitle2 = "currently online on world " + *data.Server
+	} else if lastSeen, err := timeFormat.Ago(data.LastJoin); err == nil {
+		subtitle2 = "last seen " + lastSeen
 		if data.Server != nil {
 			subtitle2 += " on world " + *data.Server
 		}
+	} else {
+		log.Printf("skipping last seen: %v", err)
 	}
-	card.SetColor(color.RGBA{R: 255, G: 255, B: 255, A: 255})
-	if err := card.LoadFontFace("statscard/fonts/comfortaa_bold.ttf", 16); err != nil {
+
+	subtitleY := float64(rankImg.Bounds().Max.Y)
+	subtitleSize, subtitleSpacing := 16.0, 23.0
+	if data.Nickname != nil && *data.Nickname != "" {
+		// a nickname takes a third line, so all three shrink to fit the header
+		if err := card.LoadFontFace(theme.Fonts.Display, 16); err != nil {
+			panic(err)
+		}
+		nickStyle := nameStyle
+		nickStyle.From = theme.Text
+		nickStyle.To = nil
+		mctext.Draw(card, mctext.Parse("~"+*data.Nickname), nickStyle, 20, subtitleY+26, 0, 0)
+
+		subtitleY += 44
+		subtitleSize, subtitleSpacing = 14, 17
+	} else {
+		subtitleY += 32
+	}
+
+	card.SetColor(theme.Text)
+	if err := card.LoadFontFace(theme.Fonts.Body, subtitleSize); err != nil {
 		panic(err)
 	}
-	card.DrawStringAnchored(subtitle1, 20, float64(rankImg.Bounds().Max.Y)+32, 0, 0)
-	card.DrawStringAnchored(subtitle2, 20, float64(rankImg.Bounds().Max.Y)+55, 0, 0)
+	card.DrawStringAnchored(subtitle1, 20, subtitleY, 0, 0)
+	card.DrawStringAnchored(subtitle2, 20, subtitleY+subtitleSpacing, 0, 0)
+	if data.Online && data.Server != nil {
+		// a dot after "currently online", pulsing when animated
+		w, h := card.MeasureString(subtitle2)
+		card.SetColor(color.NRGBA{R: 85, G: 255, B: 85, A: uint8(255 * (0.4 + 0.6*f.pulse))})
+		card.DrawCircle(20+w+10, subtitleY+subtitleSpacing-h/2, 3+f.pulse)
+		card.Fill()
+	}
 
 	// guild content
 
-	if guild != nil {
+	if guild != nil && data.Guild != nil {
 
 		guild1 := strings.ToLower(data.Guild.Rank) + " of " + guild.Prefix
 
-		// this is such a stupid data structure but idk if fixing it to solve the problem once is worth it
-		memberInfo := models.MemberInfo{}
-		if data.Guild.Rank == "OWNER" {
-			memberInfo = guild.Members.Owner[data.Username]
-		} else if data.Guild.Rank == "CHIEF" {
-			memberInfo = guild.Members.Chief[data.Username]
-		} else if data.Guild.Rank == "STRATEGIST" {
-			memberInfo = guild.Members.Strategist[data.Username]
-		} else if data.Guild.Rank == "CAPTAIN" {
-			memberInfo = guild.Members.Captain[data.Username]
-		} else if data.Guild.Rank == "RECRUITER" {
-			memberInfo = guild.Members.Recruiter[data.Username]
-		} else if data.Guild.Rank == "RECRUIT" {
-			memberInfo = guild.Members.Recruit[data.Username]
+		memberInfo := guildMember(guild, data.Username, data.Guild.Rank)
+		guild2 := ""
+		if joined, err := timeFormat.Date(memberInfo.Joined); err == nil {
+			guild2 = "since " + joined
+		} else {
+			log.Printf("skipping guild join date: %v", err)
+		}
+		guild3 := guild.Name + ", lv " + num.Int(guild.Level)
+		guild4 := num.Int(memberInfo.Contributed) + " xp contributed"
+		if memberInfo.ContributionRank != nil {
+			guild4 += " (#" + num.Int(*memberInfo.ContributionRank) + ")"
 		}
-		fmt.Println(memberInfo.Joined)
-
-		guild2 := "since " + ParseTime(memberInfo.Joined)
-		guild3 := guild.Name + ", lv " + strconv.Itoa(guild.Level)
-		guild4 := formatNumber(float64(memberInfo.Contributed)) + " xp contributed (#" + strconv.Itoa(*memberInfo.ContributionRank) + ")"
 
-		if err := card.LoadFontFace("statscard/fonts/comfortaa_bold.ttf", 20); err != nil {
+		if err := card.LoadFontFace(theme.Fonts.Body, 20); err != nil {
 			panic(err)
 		}
 		card.DrawStringAnchored(guild1, 20, imageHeight+headerHeight+30, 0, 0.5)
 
-		if err := card.LoadFontFace("statscard/fonts/comfortaa_bold.ttf", 15); err != nil {
+		if err := card.LoadFontFace(theme.Fonts.Body, 15); err != nil {
 			panic(err)
 		}
 		card.DrawStringAnchored(guild2, 20, imageHeight+headerHeight+60, 0, 0.5)
@@ -386,173 +461,106 @@ func CreateStatsCard(data models.PlayerData, outputDir string, fileName string)
 		card.DrawStringAnchored(guild4, 20, imageHeight+headerHeight+120, 0, 0.5)
 	}
 
-	// main content
-
-	// could this have been cleaner? yes. did i know struct when I started this? no
-	// should've cleaned this up instead of just slapping it into new renderer
-	// whatever, ugly code still does the job
-
-	headers := make(map[string]string)
-	headersY := make(map[string]int)
-	labels := make(map[string]string)
-	labelsY := make(map[string]int)
-	raids := make(map[string]string)
-	raidsY := make(map[string]int)
-	raidsColor := make(map[string]string)
-	valuesRight := make(map[string]string)
-	valuesRightY := make(map[string]int)
-	valuesLeft := make(map[string]string)
-	valuesLeftY := make(map[string]int)
-
-	nog := data.GlobalData.Raids.List["Nest of the Grootslangs"]
-	nol := data.GlobalData.Raids.List["Orphion's Nexus of Light"]
-	tcc := data.GlobalData.Raids.List["The Canyon Colossus"]
-	tna := data.GlobalData.Raids.List["The Nameless Anomaly"]
-
-	headers["player stats"] = "player stats"
-	headersY["player stats"] = 40
-
-	spacing := 22
-	statsY := headersY["player stats"] + 5
-
-	labels["playtime"] = "playtime"
-	labelsY["playtime"] = statsY + spacing
-	valuesRight["playtime"] = strconv.Itoa(int(math.Round(data.Playtime))) + " hr"
-	valuesRightY["playtime"] = statsY + spacing
-
-	labels["total levels"] = "total levels"
-	labelsY["total levels"] = statsY + spacing*2
-	valuesRight["total levels"] = strconv.Itoa(data.GlobalData.TotalLevel)
-	valuesRightY["total levels"] = statsY + spacing*2
-
-	labels["kills"] = "kills"
-	labelsY["kills"] = statsY + spacing*4
-	valuesRight["kills"] = strconv.Itoa(data.GlobalData.KilledMobs)
-	valuesRightY["kills"] = statsY + spacing*4
-
-	labels["chests"] = "chests"
-	labelsY["chests"] = statsY + spacing*5
-	valuesRight["chests"] = strconv.Itoa(data.GlobalData.ChestsFound)
-	valuesRightY["chests"] = statsY + spacing*5
-
-	labels["dungeons"] = "dungeons"
-	labelsY["dungeons"] = statsY + spacing*6
-	valuesRight["dungeons"] = strconv.Itoa(data.GlobalData.Dungeons.Total)
-	valuesRightY["dungeons"] = statsY + spacing*6
-
-	labels["quests"] = "quests"
-	labelsY["quests"] = statsY + spacing*7
-	valuesRight["quests"] = strconv.Itoa(data.GlobalData.CompletedQuests)
-	valuesRightY["quests"] = statsY + spacing*7
-
-	labels["wars"] = "wars"
-	labelsY["wars"] = statsY + spacing*9
-	valuesRight["wars"] = strconv.Itoa(data.GlobalData.Wars)
-	valuesRightY["wars"] = statsY + spacing*9
-
-	headers["raid completions"] = "raids completions"
-	headersY["raid completions"] = statsY + spacing*11
-
-	raidsYCoord := headersY["raid completions"] + 5
-
-	labels["total"] = "total"
-	labelsY["total"] = raidsYCoord + spacing
-	valuesLeft["total"] = strconv.Itoa(data.GlobalData.Raids.Total)
-	valuesLeftY["total"] = raidsYCoord + spacing
-
-	labels["nog"] = "nog"
-	labelsY["nog"] = raidsYCoord + spacing*3
-	raids["nog"] = strconv.Itoa(nog)
-	raidsY["nog"] = raidsYCoord + spacing*3
-	raidsColor["nog"] = "#93c47d"
-
-	labels["nol"] = "nol"
-	labelsY["nol"] = raidsYCoord + spacing*4
-	raids["nol"] = strconv.Itoa(nol)
-	raidsY["nol"] = raidsYCoord + spacing*4
-	raidsColor["nol"] = "#ffd966"
-
-	labels["tcc"] = "tcc"
-	labelsY["tcc"] = raidsYCoord + spacing*5
-	raids["tcc"] = strconv.Itoa(tcc)
-	raidsY["tcc"] = raidsYCoord + spacing*5
-	raidsColor["tcc"] = "#e06666"
-
-	labels["tna"] = "tna"
-	labelsY["tna"] = raidsYCoord + spacing*6
-	raids["tna"] = strconv.Itoa(tna)
-	raidsY["tna"] = raidsYCoord + spacing*6
-	raidsColor["tna"] = "#8e7cc3"
-
-	headers["leaderboards"] = "leaderboards"
-	headersY["leaderboards"] = raidsYCoord + spacing*8
-
-	leaderboardsY := headersY["leaderboards"] + 5
-
-	labels["completion"] = "completion"
-	labelsY["completion"] = leaderboardsY + spacing
-	valuesRight["completion"] = "#" + strconv.Itoa(data.Ranking.GlobalPlayerContent)
-	valuesRightY["completion"] = leaderboardsY + spacing
-
-	labels["professions"] = "professions"
-	labelsY["professions"] = leaderboardsY + spacing*2
-	valuesRight["professions"] = "#" + strconv.Itoa(data.Ranking.ProfessionsGlobalLevel)
-	valuesRightY["professions"] = leaderboardsY + spacing*2
-
-	labels["wars won"] = "wars won"
-	labelsY["wars won"] = leaderboardsY + spacing*4
-	valuesRight["wars won"] = "#" + strconv.Itoa(data.Ranking.WarsCompletion)
-	valuesRightY["wars won"] = leaderboardsY + spacing*4
-
-	card.SetColor(color.RGBA{R: 0, G: 0, B: 0, A: 91})
+	// main content, a header over a panel of labelled values for each part
+
+	table, err := raidTable()
+	if err != nil {
+		return err
+	}
+	raidSplit := statsRaidSplit(table, data.GlobalData.Raids, theme)
+
+	const spacing = 22
+	const labelX = 20 + imageWidth
+	const leftX, rightX = labelX + 56, labelX + 160
+	statsY := 40 + 5
+	raidsYCoord := statsY + spacing*11 + 5
+	leaderboardsY := raidsYCoord + spacing*(statsRaidLines+4) + 5
+	at := func(y int) float64 { return headerHeight + float64(y) }
+	raidY := func(i int) int { return raidsYCoord + spacing*(3+i) }
+
+	headers := []cardText{
+		{"player stats", labelX, at(40)},
+		{"raids completions", labelX, at(statsY + spacing*11)},
+		{"leaderboards", labelX, at(raidsYCoord + spacing*(statsRaidLines+4))},
+	}
+	var texts []cardText
+	stat := func(label, value string, x float64, y int) {
+		texts = append(texts, cardText{label, labelX, at(y)}, cardText{value, x, at(y)})
+	}
+	stat("playtime", num.Format(math.Round(data.Playtime*f.progress))+" hr", rightX, statsY+spacing)
+	stat("total levels", num.Int(f.count(data.GlobalData.TotalLevel)), rightX, statsY+spacing*2)
+	stat("kills", num.Int(f.count(data.GlobalData.KilledMobs)), rightX, statsY+spacing*4)
+	stat("chests", num.Int(f.count(data.GlobalData.ChestsFound)), rightX, statsY+spacing*5)
+	stat("dungeons", num.Int(f.count(data.GlobalData.Dungeons.Total)), rightX, statsY+spacing*6)
+	stat("quests", num.Int(f.count(data.GlobalData.CompletedQuests)), rightX, statsY+spacing*7)
+	stat("wars", num.Int(f.count(data.GlobalData.Wars)), rightX, statsY+spacing*9)
+	stat("total", num.Int(f.count(data.GlobalData.Raids.Total)), leftX, raidsYCoord+spacing)
+	for i, line := range raidSplit {
+		texts = append(texts, cardText{line.short, labelX, at(raidY(i))})
+	}
+	stat("completion", "#"+num.Int(data.Ranking.GlobalPlayerContent), rightX, leaderboardsY+spacing)
+	stat("professions", "#"+num.Int(data.Ranking.ProfessionsGlobalLevel), rightX, leaderboardsY+spacing*2)
+	stat("wars won", "#"+num.Int(data.Ranking.WarsCompletion), rightX, leaderboardsY+spacing*4)
+
+	card.SetColor(theme.Panel)
 	card.DrawRoundedRectangle(10+imageWidth, float64(statsY)+float64(spacing)*3.5+2, width-imageWidth-20, float64(spacing)*11-5, 15)
-	card.DrawRoundedRectangle(10+imageWidth, float64(raidsYCoord)+float64(spacing)*3.5+2, width-imageWidth-20, float64(spacing)*8-5, 15)
+	card.DrawRoundedRectangle(10+imageWidth, float64(raidsYCoord)+float64(spacing)*3.5+2, width-imageWidth-20, float64(spacing*(statsRaidLines+4))-5, 15)
 	card.DrawRoundedRectangle(10+imageWidth, float64(leaderboardsY)+float64(spacing)*3.5+2, width-imageWidth-20, float64(spacing)*6-5, 15)
 	card.Fill()
 
-	card.SetHexColor("#FFFFFF")
+	card.SetColor(theme.Text)
 
-	if err := card.LoadFontFace("statscard/fonts/comfortaa_bold.ttf", 24); err != nil {
+	if err := card.LoadFontFace(theme.Fonts.Body, 24); err != nil {
 		panic(err)
 	}
-	for key, y := range headersY {
-		card.DrawStringAnchored(headers[key], 20+imageWidth, headerHeight+float64(y), 0, 0.5)
+	for _, t := range headers {
+		card.DrawStringAnchored(t.text, t.x, t.y, 0, 0.5)
 	}
 
-	if err := card.LoadFontFace("statscard/fonts/comfortaa_bold.ttf", 16); err != nil {
+	if err := card.LoadFontFace(theme.Fonts.Body, 16); err != nil {
 		panic(err)
 	}
-	for key, y := range labelsY {
-		card.DrawStringAnchored(labels[key], 20+imageWidth, headerHeight+float64(y), 0, 0.5)
+	for _, t := range texts {
+		card.DrawStringAnchored(t.text, t.x, t.y, 0, 0.5)
 	}
-	for key, y := range valuesRightY {
-		card.DrawStringAnchored(valuesRight[key], 20+imageWidth+160, headerHeight+float64(y), 0, 0.5)
+	raidSlices := make([]slice, len(raidSplit))
+	raidCounts := make([]int, len(raidSplit))
+	for i, line := range raidSplit {
+		card.SetHexColor(line.colour)
+		card.DrawStringAnchored(num.Int(f.count(line.count)), leftX, at(raidY(i)), 0, 0.5)
+		raidSlices[i] = slice{line.count, line.colour}
+		raidCounts[i] = line.count
 	}
-	for key, y := range valuesLeftY {
-		card.DrawStringAnchored(valuesLeft[key], 20+imageWidth+90, headerHeight+float64(y), 0, 0.5)
+	drawPieChart(card, 490, float64(raidsYCoord+180), 45, 35, raidSlices, opts.Chart.startAngle())
+
+	// legend swatches and each raid's share of the donut
+	if err := card.LoadFontFace(theme.Fonts.Body, 13); err != nil {
+		panic(err)
 	}
-	for key, y := range raidsY {
-		card.SetHexColor(raidsColor[key])
-		card.DrawStringAnchored(raids[key], 20+imageWidth+90, headerHeight+float64(y), 0, 0.5)
+	shares := percentages(raidCounts)
+	for i, line := range raidSplit {
+		y := at(raidY(i))
+		card.SetHexColor(line.colour)
+		card.DrawRectangle(imageWidth+58, y-4, 8, 8)
+		card.Fill()
+		if raidCounts[i] > 0 {
+			card.SetColor(theme.Text)
+			card.DrawStringAnchored(fmt.Sprintf("%d%%", shares[i]), 20+imageWidth+104, y, 0, 0.5)
+		}
 	}
 
-	drawPieChart(card, 490, float64(raidsYCoord+180), 45, 35, map[string]int{
-		"nog": nog,
-		"nol": nol,
-		"tcc": tcc,
-		"tna": tna,
-	}, raidsColor)
-
-	footerImg, err := LoadImage("statscard/images/footer.png")
-	if err != nil {
-		return fmt.Errorf("cannot load footer image")
+	if theme.FooterImage != "" {
+		footerImg, err := LoadImage(theme.FooterImage)
+		if err != nil {
+			return fmt.Errorf("cannot load footer image")
+		}
+		card.DrawImage(footerImg, 0, height-footerHeight)
 	}
-	card.DrawImage(footerImg, 0, height-footerHeight)
 
-	if err := card.LoadFontFace("statscard/fonts/comfortaa_bold.ttf", 24); err != nil {
+	if err := card.LoadFontFace(theme.Fonts.Body, 24); err != nil {
 		panic(err)
 	}
-	card.SetHexColor("#ffffff")
+	card.SetColor(theme.Text)
 	card.DrawStringAnchored("completion", width/2, height-footerHeight+40, 0.5, 0)
 
 	classes := []string{
@@ -563,92 +571,31 @@ func CreateStatsCard(data models.PlayerData, outputDir string, fileName string)
 		"SHAMAN",
 	}
 
-	levels := map[string]int{
-		"ARCHER":   0,
-		"WARRIOR":  0,
-		"ASSASSIN": 0,
-		"MAGE":     0,
-		"SHAMAN":   0,
-	}
-
-	classColors := map[string]string{ // hsv sat 70 val 70, 50
-		"ARCHER":   "#8936b3",
-		"WARRIOR":  "#b34036",
-		"ASSASSIN": "#36b3b3",
-		"MAGE":     "#b38936",
-		"SHAMAN":   "#5fb336",
-	}
-	classPerfectionColors := map[string]string{
-		"ARCHER":   "#622680",
-		"WARRIOR":  "#802e26",
-		"ASSASSIN": "#268080",
-		"MAGE":     "#806226",
-		"SHAMAN":   "#448026",
-	}
-	for _, char := range data.Characters {
-		if char.Level > levels[char.Type] {
-			levels[char.Type] = char.Level
-		}
-	}
+	levels := ClassLevels(data)
 
-	card.LoadFontFace("statscard/fonts/minecraft.ttf", 22)
+	card.LoadFontFace(theme.Fonts.Display, 22)
 
 	for index, class := range classes {
 		x := float64(index)*width/5.0 + width/10.0
 		y := height - footerHeight + 120.0
-		drawPieChart(card, x, y, 45, 35, map[string]int{
-			"lvl": levels[class],
-			"rem": 105 - levels[class],
-		}, map[string]string{
-			"lvl": classColors[class],
-			"rem": "#000000",
-		})
-		if levels[class] == 106 {
-			drawPieChart(card, x, y, 35, 30, map[string]int{
-				"": 1,
-			}, map[string]string{
-				"": classPerfectionColors[class],
-			})
+		level := f.classLevel(class, levels[class])
+		drawPieChart(card, x, y, 45, 35, []slice{
+			{level, theme.ClassColors[class]},
+			{105 - level, theme.Inactive},
+		}, opts.Chart.startAngle())
+		if level == 106 {
+			drawPieChart(card, x, y, 35, 30, []slice{
+				{1, theme.ClassPerfectionColors[class]},
+			}, opts.Chart.startAngle())
 		}
 		classImg, err := LoadImage(fmt.Sprintf("statscard/classes/%s.png", class))
 		if err != nil {
 			// return fmt.Errorf("cannot load class images for " + class)
 		}
 		card.DrawImageAnchored(classImg, int(math.Round(x)), int(math.Round(y)-11), 0.5, 0.5)
-		card.SetHexColor(classColors[class])
-		card.DrawStringAnchored(strconv.Itoa(levels[class]), x, y+11, 0.5, 0.5)
-	}
-
-	// saving the image
-
-	saveErr := card.SavePNG(outputDir + "/" + fileName)
-	if saveErr != nil {
-		return fmt.Errorf("failed to save image: %v", saveErr)
+		card.SetHexColor(theme.ClassColors[class])
+		card.DrawStringAnchored(num.Int(level), x, y+11, 0.5, 0.5)
 	}
 
 	return nil
-
-}
-
-func fetchImage(url string) (image.Image, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch image: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Decode the image
-	img, _, err := image.Decode(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %v", err)
-	}
-
-	return img, nil
-}
-
-func fetchAvatar(username string) (image.Image, error) {
-	// Build the API URL
-	url := fmt.Sprintf("https://nmsr.nickac.dev/fullbody/%s", username)
-
-	return fetchImage(url)
 }
@@ -0,0 +1,222 @@
+package statscard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wynn_bot/avatar"
+	"wynn_bot/canvas"
+	"wynn_bot/internal/golden"
+	"wynn_bot/models"
+	"wynn_bot/timefmt"
+)
+
+var testdata string
+
+// TestMain runs from the repo root like the bot does, so asset paths resolve.
+func TestMain(m *testing.M) {
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	testdata = filepath.Join(wd, "testdata")
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// offline stands in for the render services.
+var offline = avatar.ProviderFunc(func(context.Context, avatar.Player) (image.Image, error) {
+	return testAvatar(), nil
+})
+
+// testAvatar is a blocky figure the size of an nmsr render.
+func testAvatar() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 512, 869))
+	fill := func(x0, y0, x1, y1 int, c color.NRGBA) {
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	fill(160, 20, 352, 212, color.NRGBA{R: 196, G: 140, B: 100, A: 255}) // head
+	fill(160, 212, 352, 500, color.NRGBA{R: 40, G: 120, B: 160, A: 255}) // body
+	fill(64, 212, 160, 500, color.NRGBA{R: 196, G: 140, B: 100, A: 255}) // arms
+	fill(352, 212, 448, 500, color.NRGBA{R: 196, G: 140, B: 100, A: 255})
+	fill(160, 500, 352, 849, color.NRGBA{R: 60, G: 50, B: 140, A: 255}) // legs
+	return img
+}
+
+func readFixture[T any](t *testing.T, name string) T {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join(testdata, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatalf("failed to decode %s: %v", name, err)
+	}
+	return v
+}
+
+// fixedTime keeps "last seen" the same between runs.
+var fixedTime = &timefmt.Formatter{
+	Location: time.UTC,
+	Locale:   timefmt.Locales[timefmt.DefaultLocale],
+	Now:      func() time.Time { return time.Date(2025, 1, 20, 12, 0, 0, 0, time.UTC) },
+}
+
+// renderCard runs CreateStatsCard and reads back the file it wrote.
+func renderCard(t *testing.T, data models.PlayerData, guild *models.GuildData, opts Options) image.Image {
+	t.Helper()
+	if opts.Avatars == nil {
+		opts.Avatars = offline
+	}
+	dir := t.TempDir()
+	if err := CreateStatsCard(context.Background(), data, guild, opts, dir, "card.png"); err != nil {
+		t.Fatal(err)
+	}
+	img, err := LoadImage(filepath.Join(dir, "card.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func assertGolden(t *testing.T, name string, img image.Image) {
+	t.Helper()
+	opts := golden.Default
+	opts.Dir = testdata
+	golden.Assert(t, name, img, opts)
+}
+
+func TestStatsCard(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	guild := readFixture[models.GuildData](t, "guild.json")
+
+	for _, theme := range []string{"dark", "light", "high-contrast", "mage"} {
+		t.Run(theme, func(t *testing.T) {
+			th, err := GetTheme(theme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img := renderCard(t, player, &guild, Options{Theme: th, Time: fixedTime})
+			assertGolden(t, fmt.Sprintf("card_%s", theme), img)
+		})
+	}
+}
+
+func TestStatsCardWithoutGuild(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	player.Guild = nil
+	player.Nickname = nil
+
+	assertGolden(t, "card_no_guild", renderCard(t, player, nil, Options{Time: fixedTime}))
+}
+
+func TestStatsCardWithoutAvatar(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	down := avatar.ProviderFunc(func(context.Context, avatar.Player) (image.Image, error) {
+		return nil, errors.New("service unavailable")
+	})
+
+	assertGolden(t, "card_silhouette", renderCard(t, player, nil, Options{Time: fixedTime, Avatars: down}))
+}
+
+// A raid added to the table mustn't push the leaderboards into the footer.
+func TestStatsCardWithMoreRaids(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	player.GlobalData.Raids.List["The Wartorn Palace"] = 3
+
+	table, err := LoadRaidTable(RaidTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.Raids = append(table.Raids, Raid{Name: "The Wartorn Palace", Short: "twp", Colour: "#6fa8dc"})
+	old := raidTable
+	t.Cleanup(func() { raidTable = old })
+	raidTable = func() (RaidTable, error) { return table, nil }
+
+	split := statsRaidSplit(table, player.GlobalData.Raids, Themes[DefaultThemeName])
+	if len(split) != statsRaidLines || split[len(split)-1].short != "+2" {
+		t.Errorf("split into %+v, want %d lines ending with the other two", split, statsRaidLines)
+	}
+	assertGolden(t, "card_five_raids", renderCard(t, player, nil, Options{Time: fixedTime}))
+}
+
+func TestStatsCardStopsWhenCancelled(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	// the bot shutting down while the avatar is still being fetched
+	hung := avatar.ProviderFunc(func(ctx context.Context, p avatar.Player) (image.Image, error) {
+		cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	if _, err := RenderStatsCard(ctx, player, nil, Options{Time: fixedTime, Avatars: hung}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestStatsCardIsDeterministic(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	opts := Options{Time: fixedTime, Chart: ChartOptions{Seed: SeedFor(player.UUID)}}
+
+	first := renderCard(t, player, nil, opts)
+	if _, n := golden.Diff(first, renderCard(t, player, nil, opts), golden.Options{}); n > 0 {
+		t.Errorf("rendering the same card twice changed %d pixels", n)
+	}
+}
+
+// The same card is the same bytes, so the server can give each an ETag.
+func TestStatsCardSVGIsStable(t *testing.T) {
+	player := readFixture[models.PlayerData](t, "player.json")
+	guild := readFixture[models.GuildData](t, "guild.json")
+	opts := Options{Time: fixedTime, Avatars: offline, Chart: ChartOptions{Seed: SeedFor(player.UUID)}}
+
+	render := func() []byte {
+		var buf bytes.Buffer
+		if err := WriteStatsCard(context.Background(), &buf, canvas.FormatSVG, player, &guild, opts); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	first := render()
+	// map order changes from run to run, a few tries would catch it
+	for range 5 {
+		if !bytes.Equal(first, render()) {
+			t.Fatal("rendering the same svg twice gave different bytes")
+		}
+	}
+}
+
+func TestPercentages(t *testing.T) {
+	tests := []struct {
+		values []int
+		want   []int
+	}{
+		{[]int{135, 101, 185, 158}, []int{23, 18, 32, 27}},
+		{[]int{1, 1, 1}, []int{34, 33, 33}},
+		{[]int{0, 5}, []int{0, 100}},
+		{[]int{0, 0}, []int{0, 0}},
+	}
+	for _, tt := range tests {
+		got := percentages(tt.values)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("percentages(%v) = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package statscard
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+// Fonts holds the font files a card is drawn with.
+type Fonts struct {
+	Display string // username and class levels
+	Body    string // everything else
+}
+
+// Theme is everything about a card's look that isn't layout: palette, fonts,
+// background art and how opaque the boxes drawn over it are.
+type Theme struct {
+	Name        string
+	Description string
+
+	Background      color.RGBA // fill behind the background image, or the whole card if there is none
+	BackgroundImage string     // empty means a flat Background fill
+	FooterImage     string     // empty means a flat Background fill
+
+	Box   color.NRGBA // header and guild boxes, alpha is the box opacity
+	Panel color.NRGBA // rounded stat panels, alpha is the panel opacity
+
+	Text     color.RGBA
+	Username color.RGBA // used when the player has no legacy rank colour
+	Inactive string     // remaining part of the class completion rings
+
+	ClassColors           map[string]string
+	ClassPerfectionColors map[string]string
+	RaidColors            map[string]string
+	DungeonColors         map[string]string // "regular", "corrupted" and "never" for ones not done
+
+	Fonts Fonts
+}
+
+const DefaultThemeName = "dark"
+
+var defaultFonts = Fonts{
+	Display: "statscard/fonts/minecraft.ttf",
+	Body:    "statscard/fonts/comfortaa_bold.ttf",
+}
+
+// comfortaa's round letters run together at small sizes, nunito's open ones
+// stay readable
+var highContrastFonts = Fonts{
+	Display: "statscard/fonts/minecraft.ttf",
+	Body:    "statscard/fonts/nunito.ttf",
+}
+
+var classColors = map[string]string{ // hsv sat 70 val 70, 50
+	"ARCHER":   "#8936b3",
+	"WARRIOR":  "#b34036",
+	"ASSASSIN": "#36b3b3",
+	"MAGE":     "#b38936",
+	"SHAMAN":   "#5fb336",
+}
+
+var classPerfectionColors = map[string]string{
+	"ARCHER":   "#622680",
+	"WARRIOR":  "#802e26",
+	"ASSASSIN": "#268080",
+	"MAGE":     "#806226",
+	"SHAMAN":   "#448026",
+}
+
+var raidColors = map[string]string{
+	"nog": "#93c47d",
+	"nol": "#ffd966",
+	"tcc": "#e06666",
+	"tna": "#8e7cc3",
+}
+
+var dungeonColors = map[string]string{
+	"regular":   "#6fa8dc",
+	"corrupted": "#cc4125",
+	"never":     "#ff7f7f",
+}
+
+var darkTheme = Theme{
+	Name:                  "dark",
+	Description:           "the original purple card",
+	Background:            color.RGBA{R: 19, G: 0, B: 25, A: 255},
+	BackgroundImage:       "statscard/images/background.png",
+	FooterImage:           "statscard/images/footer.png",
+	Box:                   color.NRGBA{R: 0, G: 0, B: 0, A: 120},
+	Panel:                 color.NRGBA{R: 0, G: 0, B: 0, A: 91},
+	Text:                  color.RGBA{R: 255, G: 255, B: 255, A: 255},
+	Username:              color.RGBA{R: 221, G: 225, B: 218, A: 255},
+	Inactive:              "#000000",
+	ClassColors:           classColors,
+	ClassPerfectionColors: classPerfectionColors,
+	RaidColors:            raidColors,
+	DungeonColors:         dungeonColors,
+	Fonts:                 defaultFonts,
+}
+
+var lightTheme = Theme{
+	Name:                  "light",
+	Description:           "dark text on a pale background",
+	Background:            color.RGBA{R: 238, G: 234, B: 242, A: 255},
+	Box:                   color.NRGBA{R: 255, G: 255, B: 255, A: 160},
+	Panel:                 color.NRGBA{R: 250, G: 248, B: 252, A: 215},
+	Text:                  color.RGBA{R: 34, G: 26, B: 42, A: 255},
+	Username:              color.RGBA{R: 70, G: 56, B: 84, A: 255},
+	Inactive:              "#d6cfdd",
+	ClassColors:           classColors,
+	ClassPerfectionColors: classPerfectionColors,
+	RaidColors: map[string]string{
+		"nog": "#5a8f43",
+		"nol": "#b8901a",
+		"tcc": "#b83a3a",
+		"tna": "#5f4aa0",
+	},
+	DungeonColors: map[string]string{
+		"regular":   "#3d78b0",
+		"corrupted": "#a8321a",
+		"never":     "#c0392b",
+	},
+	Fonts: defaultFonts,
+}
+
+var highContrastTheme = Theme{
+	Name:        "high-contrast",
+	Description: "pure black and saturated colours",
+	Background:  color.RGBA{R: 0, G: 0, B: 0, A: 255},
+	Box:         color.NRGBA{R: 255, G: 255, B: 255, A: 36},
+	Panel:       color.NRGBA{R: 255, G: 255, B: 255, A: 36},
+	Text:        color.RGBA{R: 255, G: 255, B: 255, A: 255},
+	Username:    color.RGBA{R: 255, G: 255, B: 85, A: 255},
+	Inactive:    "#3a3a3a",
+	ClassColors: map[string]string{
+		"ARCHER":   "#d45cff",
+		"WARRIOR":  "#ff5040",
+		"ASSASSIN": "#40ffff",
+		"MAGE":     "#ffc640",
+		"SHAMAN":   "#70ff40",
+	},
+	ClassPerfectionColors: map[string]string{
+		"ARCHER":   "#8a20b0",
+		"WARRIOR":  "#b01a10",
+		"ASSASSIN": "#10b0b0",
+		"MAGE":     "#b08010",
+		"SHAMAN":   "#30b010",
+	},
+	RaidColors: map[string]string{
+		"nog": "#55ff55",
+		"nol": "#ffff55",
+		"tcc": "#ff5555",
+		"tna": "#aa88ff",
+	},
+	DungeonColors: map[string]string{
+		"regular":   "#55ffff",
+		"corrupted": "#ff5555",
+		"never":     "#ff55ff",
+	},
+	Fonts: highContrastFonts,
+}
+
+// classTheme builds a flat theme tinted with a class colour.
+func classTheme(class string) Theme {
+	accent := hexColor(classColors[class])
+	t := darkTheme
+	t.Name = strings.ToLower(class)
+	t.Description = "tinted " + strings.ToLower(class) + " colours"
+	t.Background = scaleColor(accent, 0.18)
+	t.BackgroundImage = ""
+	t.FooterImage = ""
+	t.Box = color.NRGBA{R: 0, G: 0, B: 0, A: 110}
+	t.Panel = withAlpha(scaleColor(accent, 0.6), 70)
+	t.Username = blendColor(accent, color.RGBA{R: 255, G: 255, B: 255, A: 255}, 0.45)
+	return t
+}
+
+// Themes lists the built-in themes by name.
+var Themes = func() map[string]Theme {
+	themes := map[string]Theme{
+		darkTheme.Name:         darkTheme,
+		lightTheme.Name:        lightTheme,
+		highContrastTheme.Name: highContrastTheme,
+	}
+	for class := range classColors {
+		t := classTheme(class)
+		themes[t.Name] = t
+	}
+	return themes
+}()
+
+// ThemeNames returns the built-in theme names, default first and the rest sorted.
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		if name != DefaultThemeName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultThemeName}, names...)
+}
+
+// GetTheme looks up a built-in theme, an empty name gives the default.
+func GetTheme(name string) (Theme, error) {
+	if name == "" {
+		name = DefaultThemeName
+	}
+	t, ok := Themes[strings.ToLower(name)]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q", name)
+	}
+	return t, nil
+}
+
+func hexColor(hex string) color.RGBA {
+	var r, g, b uint8
+	fmt.Sscanf(strings.TrimPrefix(hex, "#"), "%02x%02x%02x", &r, &g, &b)
+	return color.RGBA{R: r, G: g, B: b, A: 255}
+}
+
+// hexString is the inverse of hexColor, for the drawing helpers that take hex.
+func hexString(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func scaleColor(c color.RGBA, factor float64) color.RGBA {
+	return color.RGBA{
+		R: uint8(float64(c.R) * factor),
+		G: uint8(float64(c.G) * factor),
+		B: uint8(float64(c.B) * factor),
+		A: c.A,
+	}
+}
+
+func blendColor(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 { return uint8(float64(x)*(1-t) + float64(y)*t) }
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: mix(a.A, b.A)}
+}
+
+func withAlpha(c color.RGBA, a uint8) color.NRGBA {
+	return color.NRGBA{R: c.R, G: c.G, B: c.B, A: a}
+}
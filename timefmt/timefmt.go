@@ -0,0 +1,272 @@
+package timefmt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale holds the words and date layout a Formatter writes with.
+type Locale struct {
+	Tag string
+
+	// DateLayout uses {d}, {dd}, {mon} and {yyyy} placeholders so month names
+	// can come from the locale instead of Go's English ones.
+	DateLayout string
+	Months     [12]string
+
+	Units   map[Unit][2]string // singular and plural
+	Past    string             // e.g. "%s ago"
+	Future  string             // e.g. "in %s"
+	JustNow string
+}
+
+type Unit int
+
+const (
+	Second Unit = iota
+	Minute
+	Hour
+	Day
+	Month
+	Year
+)
+
+var Locales = map[string]Locale{
+	"en-US": {
+		Tag:        "en-US",
+		DateLayout: "{mon} {dd}, {yyyy}",
+		Months:     [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		Units:      englishUnits,
+		Past:       "%s ago",
+		Future:     "in %s",
+		JustNow:    "just now",
+	},
+	"en-GB": {
+		Tag:        "en-GB",
+		DateLayout: "{dd} {mon} {yyyy}",
+		Months:     [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		Units:      englishUnits,
+		Past:       "%s ago",
+		Future:     "in %s",
+		JustNow:    "just now",
+	},
+	"de": {
+		Tag:        "de",
+		DateLayout: "{d}. {mon} {yyyy}",
+		Months:     [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		Units: map[Unit][2]string{
+			Second: {"Sekunde", "Sekunden"},
+			Minute: {"Minute", "Minuten"},
+			Hour:   {"Stunde", "Stunden"},
+			Day:    {"Tag", "Tagen"},
+			Month:  {"Monat", "Monaten"},
+			Year:   {"Jahr", "Jahren"},
+		},
+		Past:    "vor %s",
+		Future:  "in %s",
+		JustNow: "gerade eben",
+	},
+	"fr": {
+		Tag:        "fr",
+		DateLayout: "{d} {mon} {yyyy}",
+		Months:     [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		Units: map[Unit][2]string{
+			Second: {"seconde", "secondes"},
+			Minute: {"minute", "minutes"},
+			Hour:   {"heure", "heures"},
+			Day:    {"jour", "jours"},
+			Month:  {"mois", "mois"},
+			Year:   {"an", "ans"},
+		},
+		Past:    "il y a %s",
+		Future:  "dans %s",
+		JustNow: "à l'instant",
+	},
+}
+
+var englishUnits = map[Unit][2]string{
+	Second: {"second", "seconds"},
+	Minute: {"minute", "minutes"},
+	Hour:   {"hour", "hours"},
+	Day:    {"day", "days"},
+	Month:  {"month", "months"},
+	Year:   {"year", "years"},
+}
+
+const DefaultLocale = "en-US"
+
+// LookupLocale finds the closest locale for a tag like "en-GB" or "de-AT",
+// falling back to the language and then to DefaultLocale.
+func LookupLocale(tag string) Locale {
+	if l, ok := Locales[tag]; ok {
+		return l
+	}
+	lang, _, _ := strings.Cut(tag, "-")
+	if l, ok := Locales[lang]; ok {
+		return l
+	}
+	if lang == "en" {
+		return Locales["en-GB"]
+	}
+	return Locales[DefaultLocale]
+}
+
+// Formatter writes wynncraft api timestamps for one user's timezone and locale.
+type Formatter struct {
+	Location *time.Location
+	Locale   Locale
+	Now      func() time.Time // time.Now if nil
+}
+
+// New builds a Formatter, an empty timezone means UTC.
+func New(timezone, locale string) (*Formatter, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q: %v", timezone, err)
+	}
+	return &Formatter{Location: loc, Locale: LookupLocale(locale)}, nil
+}
+
+// Default formats in UTC with the default locale.
+func Default() *Formatter {
+	return &Formatter{Location: time.UTC, Locale: Locales[DefaultLocale]}
+}
+
+func (f *Formatter) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+// Parse reads a timestamp as the api sends it.
+func Parse(raw string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q: %v", raw, err)
+	}
+	return t, nil
+}
+
+// Date formats a raw timestamp as a calendar date in the formatter's timezone.
+func (f *Formatter) Date(raw string) (string, error) {
+	t, err := Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return f.FormatDate(t), nil
+}
+
+// FormatDate formats t as a calendar date in the formatter's timezone.
+func (f *Formatter) FormatDate(t time.Time) string {
+	t = t.In(f.Location)
+	return strings.NewReplacer(
+		"{dd}", fmt.Sprintf("%02d", t.Day()),
+		"{d}", strconv.Itoa(t.Day()),
+		"{mon}", f.Locale.Months[t.Month()-1],
+		"{yyyy}", strconv.Itoa(t.Year()),
+	).Replace(f.Locale.DateLayout)
+}
+
+// Ago formats a raw timestamp relative to now, e.g. "3 days ago".
+func (f *Formatter) Ago(raw string) (string, error) {
+	t, err := Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return f.Relative(t), nil
+}
+
+// Relative formats t relative to now. Months and years are counted on the
+// calendar in the formatter's timezone, so Jan 15 to Mar 15 is always two months.
+func (f *Formatter) Relative(t time.Time) string {
+	now := f.now().In(f.Location)
+	t = t.In(f.Location)
+
+	format := f.Locale.Past
+	from, to := t, now
+	if t.After(now) {
+		format = f.Locale.Future
+		from, to = now, t
+	}
+
+	value, unit := Between(from, to)
+	if unit == Second && value == 0 {
+		return f.Locale.JustNow
+	}
+	return fmt.Sprintf(format, f.quantity(value, unit))
+}
+
+func (f *Formatter) quantity(value int, unit Unit) string {
+	names := f.Locale.Units[unit]
+	if value == 1 {
+		return fmt.Sprintf("%d %s", value, names[0])
+	}
+	return fmt.Sprintf("%d %s", value, names[1])
+}
+
+// Between returns the largest whole unit between from and to, which must not be after to.
+// Seconds, minutes and hours are elapsed time, days and up follow the calendar,
+// so a 23 hour day across a DST change still counts as one day.
+func Between(from, to time.Time) (int, Unit) {
+	d := to.Sub(from)
+	switch {
+	case d < time.Minute:
+		return int(d / time.Second), Second
+	case d < time.Hour:
+		return int(d / time.Minute), Minute
+	case from.AddDate(0, 0, 1).After(to):
+		return int(d / time.Hour), Hour
+	}
+
+	years := to.Year() - from.Year()
+	if addMonths(from, 12*years).After(to) {
+		years--
+	}
+	if years > 0 {
+		return years, Year
+	}
+
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if addMonths(from, months).After(to) {
+		months--
+	}
+	if months > 0 {
+		return months, Month
+	}
+
+	days := 1
+	for !from.AddDate(0, 0, days+1).After(to) {
+		days++
+	}
+	return days, Day
+}
+
+// addMonths moves t by n calendar months. A day the target month doesn't
+// have becomes its last, where AddDate would run on into the next month and
+// make Jan 31 to Feb 28 less than a month.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	day := min(t.Day(), first.AddDate(0, 1, -1).Day())
+	return time.Date(first.Year(), first.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// Style is a discord timestamp format letter.
+type Style string
+
+const (
+	ShortTime     Style = "t"
+	LongTime      Style = "T"
+	ShortDate     Style = "d"
+	LongDate      Style = "D"
+	ShortDateTime Style = "f"
+	LongDateTime  Style = "F"
+	RelativeTime  Style = "R"
+)
+
+// Discord returns a dynamic timestamp that each client renders in its own timezone and language.
+func Discord(t time.Time, style Style) string {
+	return fmt.Sprintf("<t:%d:%s>", t.Unix(), style)
+}
@@ -0,0 +1,149 @@
+package timefmt
+
+import (
+	"testing"
+	"time"
+)
+
+func fixedClock(raw string) func() time.Time {
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		panic(err)
+	}
+	return func() time.Time { return t }
+}
+
+func mustFormatter(t *testing.T, timezone, locale, now string) *Formatter {
+	t.Helper()
+	f, err := New(timezone, locale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Now = fixedClock(now)
+	return f
+}
+
+func TestDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		locale   string
+		raw      string
+		want     string
+	}{
+		{"utc us", "UTC", "en-US", "2020-06-27T14:03:11.123Z", "Jun 27, 2020"},
+		{"utc gb", "UTC", "en-GB", "2020-06-27T14:03:11.123Z", "27 Jun 2020"},
+		{"german", "UTC", "de", "2020-03-05T10:00:00Z", "5. März 2020"},
+		{"french", "UTC", "fr", "2020-02-05T10:00:00Z", "5 févr. 2020"},
+		{"region falls back to language", "UTC", "de-AT", "2020-03-05T10:00:00Z", "5. März 2020"},
+		{"unknown locale falls back", "UTC", "xx", "2020-06-27T14:03:11Z", "Jun 27, 2020"},
+		{"timezone moves the day back", "America/New_York", "en-US", "2024-12-10T02:00:00Z", "Dec 09, 2024"},
+		{"timezone moves the day forward", "Asia/Tokyo", "en-US", "2024-12-31T20:00:00Z", "Jan 01, 2025"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mustFormatter(t, tt.timezone, tt.locale, "2025-01-01T00:00:00Z")
+			got, err := f.Date(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Date(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgo(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		locale   string
+		now      string
+		raw      string
+		want     string
+	}{
+		{"now", "UTC", "en-US", "2025-01-15T12:00:00Z", "2025-01-15T12:00:00Z", "just now"},
+		{"one second", "UTC", "en-US", "2025-01-15T12:00:01Z", "2025-01-15T12:00:00Z", "1 second ago"},
+		{"seconds", "UTC", "en-US", "2025-01-15T12:00:59Z", "2025-01-15T12:00:00Z", "59 seconds ago"},
+		{"minutes", "UTC", "en-US", "2025-01-15T12:42:00Z", "2025-01-15T12:00:00Z", "42 minutes ago"},
+		{"hour", "UTC", "en-US", "2025-01-15T13:30:00Z", "2025-01-15T12:00:00Z", "1 hour ago"},
+		{"day", "UTC", "en-US", "2025-01-16T12:00:00Z", "2025-01-15T12:00:00Z", "1 day ago"},
+		{"almost two days", "UTC", "en-US", "2025-01-17T11:59:00Z", "2025-01-15T12:00:00Z", "1 day ago"},
+		// 28 days is a whole month in february but not in january
+		{"february month", "UTC", "en-US", "2025-03-01T12:00:00Z", "2025-02-01T12:00:00Z", "1 month ago"},
+		{"january is not a month after 28 days", "UTC", "en-US", "2025-01-29T12:00:00Z", "2025-01-01T12:00:00Z", "28 days ago"},
+		{"31 days", "UTC", "en-US", "2025-02-01T12:00:00Z", "2025-01-01T12:00:00Z", "1 month ago"},
+		{"eleven months", "UTC", "en-US", "2025-12-31T12:00:00Z", "2025-01-01T12:00:00Z", "11 months ago"},
+		// 360 days used to count as a year with 30 day months
+		{"360 days is not a year", "UTC", "en-US", "2025-12-27T12:00:00Z", "2025-01-01T12:00:00Z", "11 months ago"},
+		{"year", "UTC", "en-US", "2025-01-01T12:00:00Z", "2024-01-01T12:00:00Z", "1 year ago"},
+		// a day the month doesn't have counts as its last day
+		{"leap day", "UTC", "en-US", "2025-02-28T12:00:00Z", "2024-02-29T12:00:00Z", "1 year ago"},
+		{"leap day a day early", "UTC", "en-US", "2025-02-27T12:00:00Z", "2024-02-29T12:00:00Z", "11 months ago"},
+		{"end of january to end of february", "UTC", "en-US", "2025-02-28T12:00:00Z", "2025-01-31T12:00:00Z", "1 month ago"},
+		{"end of january to march", "UTC", "en-US", "2025-03-01T12:00:00Z", "2025-01-31T12:00:00Z", "1 month ago"},
+		{"end of march to end of april", "UTC", "en-US", "2025-04-30T12:00:00Z", "2025-03-31T12:00:00Z", "1 month ago"},
+		{"end of march short of april's end", "UTC", "en-US", "2025-04-29T12:00:00Z", "2025-03-31T12:00:00Z", "29 days ago"},
+		{"end of january to end of april", "UTC", "en-US", "2025-04-30T12:00:00Z", "2025-01-31T12:00:00Z", "3 months ago"},
+		{"years", "UTC", "en-US", "2025-06-27T00:00:00Z", "2020-06-27T14:03:11.123Z", "4 years ago"},
+		{"future", "UTC", "en-US", "2025-01-15T12:00:00Z", "2025-01-18T12:00:00Z", "in 3 days"},
+		{"german", "UTC", "de", "2025-01-15T12:00:00Z", "2025-01-12T12:00:00Z", "vor 3 Tagen"},
+		{"french", "UTC", "fr", "2025-01-15T12:00:00Z", "2024-01-15T12:00:00Z", "il y a 1 an"},
+		// the clocks go forward on 2025-03-30 in berlin, so that day is only 23 hours long
+		{"dst day", "Europe/Berlin", "en-US", "2025-03-30T22:00:00Z", "2025-03-29T23:00:00Z", "1 day ago"},
+		{"month across dst", "Europe/Berlin", "en-US", "2025-04-15T10:00:00Z", "2025-03-15T11:00:00Z", "1 month ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mustFormatter(t, tt.timezone, tt.locale, tt.now)
+			got, err := f.Ago(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Ago(%q) at %s = %q, want %q", tt.raw, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidTimestamp(t *testing.T) {
+	f := mustFormatter(t, "UTC", "en-US", "2025-01-01T00:00:00Z")
+
+	for _, raw := range []string{"", "yesterday", "2025-01-01"} {
+		if got, err := f.Date(raw); err == nil {
+			t.Errorf("Date(%q) = %q, want an error", raw, got)
+		}
+		if got, err := f.Ago(raw); err == nil {
+			t.Errorf("Ago(%q) = %q, want an error", raw, got)
+		}
+	}
+}
+
+func TestUnknownTimezone(t *testing.T) {
+	if _, err := New("Mars/Olympus_Mons", "en-US"); err == nil {
+		t.Error("New with an unknown timezone should fail")
+	}
+}
+
+func TestDiscord(t *testing.T) {
+	ts := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		style Style
+		want  string
+	}{
+		{RelativeTime, "<t:1736942400:R>"},
+		{LongDate, "<t:1736942400:D>"},
+		{ShortDateTime, "<t:1736942400:f>"},
+	}
+
+	for _, tt := range tests {
+		if got := Discord(ts, tt.style); got != tt.want {
+			t.Errorf("Discord(%s) = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+}
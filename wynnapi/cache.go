@@ -0,0 +1,155 @@
+package wynnapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Responses are kept for CacheTTL so the bot and the web server asking for
+// the same player within a few minutes only costs one request.
+var CacheTTL = 2 * time.Minute
+
+type cacheEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+var responses = &cache{entries: map[string]cacheEntry{}}
+
+func (c *cache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.body, true
+}
+
+func (c *cache) put(key string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	// drop expired entries as we go so the map doesn't grow forever
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{body: body, expires: now.Add(CacheTTL)}
+}
+
+// limiter is a token bucket: up to burst requests at once, refilled at rate
+// per second.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// the api allows a few hundred requests a minute per ip, stay well under it
+var requests = newLimiter(2, 10)
+
+func newLimiter(rate, burst float64) *limiter {
+	return &limiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait blocks until a request may be sent or ctx ends.
+func (l *limiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// savedEntry is how a cache entry is written to disk.
+type savedEntry struct {
+	Body    json.RawMessage `json:"body"`
+	Expires time.Time       `json:"expires"`
+}
+
+// SaveCache writes the responses that haven't expired to path, so a restart
+// doesn't have to fetch them again.
+func SaveCache(path string) error {
+	responses.mu.Lock()
+	saved := make(map[string]savedEntry, len(responses.entries))
+	now := time.Now()
+	for url, entry := range responses.entries {
+		if now.Before(entry.expires) && json.Valid(entry.body) {
+			saved[url] = savedEntry{Body: entry.body, Expires: entry.expires}
+		}
+	}
+	responses.mu.Unlock()
+
+	raw, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write cache: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace cache: %v", err)
+	}
+	return nil
+}
+
+// LoadCache reads back what SaveCache wrote, skipping entries that expired
+// in the meantime. A missing file is not an error.
+func LoadCache(path string) error {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cache: %v", err)
+	}
+	var saved map[string]savedEntry
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		return fmt.Errorf("failed to decode cache: %v", err)
+	}
+
+	responses.mu.Lock()
+	defer responses.mu.Unlock()
+	now := time.Now()
+	for url, entry := range saved {
+		if now.Before(entry.Expires) {
+			responses.entries[url] = cacheEntry{body: entry.Body, expires: entry.Expires}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package wynnapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wynn_bot/models"
+)
+
+func TestCacheExpires(t *testing.T) {
+	defer func(ttl time.Duration) { CacheTTL = ttl }(CacheTTL)
+	c := &cache{entries: map[string]cacheEntry{}}
+
+	CacheTTL = time.Hour
+	c.put("fresh", []byte("a"))
+	if body, ok := c.get("fresh"); !ok || string(body) != "a" {
+		t.Errorf("get(fresh) = %q, %v", body, ok)
+	}
+
+	CacheTTL = -time.Second
+	c.put("stale", []byte("b"))
+	if _, ok := c.get("stale"); ok {
+		t.Error("an expired entry was returned")
+	}
+	if _, ok := c.entries["stale"]; ok {
+		t.Error("an expired entry was kept")
+	}
+}
+
+func TestLimiterBurst(t *testing.T) {
+	l := newLimiter(1000, 3)
+	start := time.Now()
+	for range 3 {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Error("requests within the burst were delayed")
+	}
+	l.wait(context.Background())
+	if l.tokens >= 1 {
+		t.Errorf("%v tokens left after spending the burst", l.tokens)
+	}
+}
+
+func TestLimiterWaitIsCancelled(t *testing.T) {
+	l := newLimiter(0.001, 1)
+	l.wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait() = %v, want the deadline", err)
+	}
+}
+
+func TestSaveCache(t *testing.T) {
+	defer func(entries map[string]cacheEntry) { responses.entries = entries }(responses.entries)
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	responses.entries = map[string]cacheEntry{
+		"fresh": {body: []byte(`{"a":1}`), expires: time.Now().Add(time.Hour)},
+		"stale": {body: []byte(`{"b":2}`), expires: time.Now().Add(-time.Second)},
+	}
+	if err := SaveCache(path); err != nil {
+		t.Fatal(err)
+	}
+
+	responses.entries = map[string]cacheEntry{}
+	if err := LoadCache(path); err != nil {
+		t.Fatal(err)
+	}
+	if body, ok := responses.get("fresh"); !ok || string(body) != `{"a":1}` {
+		t.Errorf("get(fresh) = %q, %v after loading", body, ok)
+	}
+	if _, ok := responses.entries["stale"]; ok {
+		t.Error("an expired entry was saved")
+	}
+
+	if err := LoadCache(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("a missing cache file failed: %v", err)
+	}
+}
+
+// A 200 that doesn't decode is an api hiccup, the next request should try again.
+func TestMalformedResponseIsNotCached(t *testing.T) {
+	defer func(entries map[string]cacheEntry) { responses.entries = entries }(responses.entries)
+	responses.entries = map[string]cacheEntry{}
+
+	bodies := []string{`{"username":`, `{"username":"starfaiien"}`}
+	requests := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(bodies[min(requests, len(bodies)-1)]))
+		requests++
+	}))
+	defer api.Close()
+
+	var player models.PlayerData
+	if err := get(context.Background(), "player", api.URL, &player); !errors.Is(err, ErrDecode) {
+		t.Fatalf("got %v, want ErrDecode", err)
+	}
+	if err := get(context.Background(), "player", api.URL, &player); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(context.Background(), "player", api.URL, &player); err != nil || player.Username != "starfaiien" {
+		t.Errorf("got %q, %v", player.Username, err)
+	}
+	if requests != 2 {
+		t.Errorf("made %d requests, want the broken one retried and the good one cached", requests)
+	}
+}
+
+func TestRankingSkipsNonIntegers(t *testing.T) {
+	var ranking models.Ranking
+	raw := `{"warsCompletion": 12, "orphionCompletion": null, "newBoard": "n/a", "other": 3.5, "guildLevel": 7}`
+	if err := json.Unmarshal([]byte(raw), &ranking); err != nil {
+		t.Fatal(err)
+	}
+	if ranking.WarsCompletion != 12 || ranking.OrphionCompletion != 0 {
+		t.Errorf("fields %+v", ranking)
+	}
+	if want := map[string]int{"warsCompletion": 12, "guildLevel": 7}; !maps.Equal(ranking.All, want) {
+		t.Errorf("All = %v, want %v", ranking.All, want)
+	}
+}
@@ -0,0 +1,20 @@
+package wynnapi
+
+import "wynn_bot/metrics"
+
+var (
+	requestDuration = metrics.Default.NewHistogram("wynn_api_request_duration_seconds",
+		"Time the wynncraft api took to answer, by endpoint and status.", nil, "endpoint", "status")
+	cacheLookups = metrics.Default.NewCounter("wynn_api_cache_lookups_total",
+		"Api responses looked up in the cache, by whether they were there.", "result")
+	_ = metrics.Default.NewGauge("wynn_api_cache_hit_ratio",
+		"Share of api lookups answered from the cache since the bot started.", cacheHitRatio)
+)
+
+func cacheHitRatio() float64 {
+	hits, misses := cacheLookups.Value("hit"), cacheLookups.Value("miss")
+	if hits+misses == 0 {
+		return 0
+	}
+	return hits / (hits + misses)
+}
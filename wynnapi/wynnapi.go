@@ -0,0 +1,126 @@
+package wynnapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"wynn_bot/models"
+)
+
+const baseURL = "https://api.wynncraft.com/v3"
+
+// requestTimeout bounds one request, callers' contexts can end it sooner
+const requestTimeout = 10 * time.Second
+
+var (
+	ErrRequest  = errors.New("failed to access the api")
+	ErrNotFound = errors.New("not found")
+	ErrDecode   = errors.New("failed to decode response")
+)
+
+// GetPlayer fetches the full player profile by username or uuid.
+func GetPlayer(ctx context.Context, username string) (models.PlayerData, error) {
+	var playerData models.PlayerData
+	err := get(ctx, "player", fmt.Sprintf("%s/player/%s?fullResult", baseURL, url.PathEscape(username)), &playerData)
+	return playerData, err
+}
+
+// GetGuild fetches a guild by its full name.
+func GetGuild(ctx context.Context, name string) (models.GuildData, error) {
+	var guildData models.GuildData
+	err := get(ctx, "guild", fmt.Sprintf("%s/guild/%s", baseURL, url.PathEscape(name)), &guildData)
+	return guildData, err
+}
+
+// GetGuildByPrefix fetches a guild by its tag, e.g. SEQ.
+func GetGuildByPrefix(ctx context.Context, prefix string) (models.GuildData, error) {
+	var guildData models.GuildData
+	err := get(ctx, "guild_prefix", fmt.Sprintf("%s/guild/prefix/%s", baseURL, url.PathEscape(prefix)), &guildData)
+	return guildData, err
+}
+
+// FindGuild looks a guild up by name, then by prefix for short tags like SEQ.
+func FindGuild(ctx context.Context, query string) (models.GuildData, error) {
+	guild, err := GetGuild(ctx, query)
+	if errors.Is(err, ErrNotFound) {
+		return GetGuildByPrefix(ctx, query)
+	}
+	return guild, err
+}
+
+// get fetches url into v, answering from the shared cache when it can and
+// waiting on the shared rate limiter when it can't. endpoint names the
+// request in the metrics. Only bodies that decode are cached, so a broken
+// response is asked for again next time instead of served for CacheTTL.
+func get(ctx context.Context, endpoint, url string, v any) error {
+	body, cached := responses.get(url)
+	if cached {
+		cacheLookups.Inc("hit")
+	} else {
+		cacheLookups.Inc("miss")
+		var err error
+		if body, err = timedFetch(ctx, endpoint, url); err != nil {
+			return err
+		}
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+	if !cached {
+		responses.put(url, body)
+	}
+	return nil
+}
+
+// timedFetch waits its turn on the rate limiter, then fetches url and
+// records how long the api took.
+func timedFetch(ctx context.Context, endpoint, url string) ([]byte, error) {
+	if err := requests.wait(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRequest, err)
+	}
+
+	start := time.Now()
+	body, err := fetch(ctx, url)
+	status := "ok"
+	if errors.Is(err, ErrNotFound) {
+		status = "not_found"
+	} else if err != nil {
+		status = "error"
+	}
+	requestDuration.Observe(time.Since(start).Seconds(), endpoint, status)
+	return body, err
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRequest, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %s", ErrRequest, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRequest, err)
+	}
+	return body, nil
+}